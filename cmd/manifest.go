@@ -0,0 +1,101 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/heindl/wikivents/fetch/endpoint"
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// manifestName is written beside the output files once they are complete.
+const manifestName = "manifest.json"
+
+// manifest records how a run was made and what it wrote.
+type manifest struct {
+	StartYear      int                 `json:"start_year"`
+	EndYear        int                 `json:"end_year"`
+	Format         string              `json:"format"`
+	Compression    compression         `json:"compression"`
+	References     string              `json:"references"`
+	Languages      []string            `json:"languages,omitempty"`
+	PredicateNames string              `json:"predicate_names"`
+	ShardBy        string              `json:"shard_by,omitempty"`
+	ShardSize      string              `json:"shard_size,omitempty"`
+	Deterministic  bool                `json:"deterministic"`
+	Dates          bool                `json:"dates"`
+	Endpoint       string              `json:"endpoint"`
+	Templates      []endpoint.Template `json:"templates"`
+	Started        time.Time           `json:"started"`
+	Finished       time.Time           `json:"finished"`
+	parse.Stats
+	Files []manifestFile `json:"files"`
+}
+
+type manifestFile struct {
+	Name   string `json:"name"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// write writes the manifest to a temporary file and renames it, as with the other outputs.
+func (Ω *manifest) write(directory string) error {
+	b, err := json.MarshalIndent(Ω, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not encode manifest")
+	}
+	filePath := filepath.Join(directory, manifestName)
+	temp := filepath.Join(directory, "."+manifestName+".tmp")
+	if err := os.WriteFile(temp, append(b, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "could not write %s", temp)
+	}
+	return errors.Wrapf(os.Rename(temp, filePath), "could not rename %s to %s", temp, filePath)
+}
+
+// hashWriter counts and hashes what is written through it.
+type hashWriter struct {
+	io.Writer
+	hash  hash.Hash
+	bytes int64
+}
+
+func newHashWriter(w io.Writer) *hashWriter {
+	h := sha256.New()
+	return &hashWriter{Writer: io.MultiWriter(w, h), hash: h}
+}
+
+func (Ω *hashWriter) Write(p []byte) (int, error) {
+	n, err := Ω.Writer.Write(p)
+	Ω.bytes += int64(n)
+	return n, err
+}
+
+func (Ω *hashWriter) file(name string) manifestFile {
+	return manifestFile{Name: name, Bytes: Ω.bytes, SHA256: hex.EncodeToString(Ω.hash.Sum(nil))}
+}
+
+// checksumFile reads the file at the path to record it under the name.
+func checksumFile(name, filePath string) (manifestFile, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return manifestFile{}, errors.Wrapf(err, "could not open %s", filePath)
+	}
+	defer f.Close()
+	w := newHashWriter(io.Discard)
+	if _, err := io.Copy(w, f); err != nil {
+		return manifestFile{}, errors.Wrapf(err, "could not read %s", filePath)
+	}
+	return w.file(name), nil
+}
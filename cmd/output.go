@@ -0,0 +1,368 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package cmd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/heindl/wikivents/fetch/extsort"
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/heindl/wikivents/fetch/sink"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// stdoutDirectory is the output directory that writes the main file of a format to stdout.
+const stdoutDirectory = "-"
+
+// compression is how the output files are compressed.
+type compression string
+
+const (
+	compressionNone = compression("none")
+	compressionGzip = compression("gzip")
+	compressionZstd = compression("zstd")
+)
+
+func newCompression(s string) (compression, error) {
+	switch c := compression(s); c {
+	case compressionNone, compressionGzip, compressionZstd:
+		return c, nil
+	}
+	return "", errors.Errorf("unknown compression [%s], expected none, gzip or zstd", s)
+}
+
+// extension is appended to the names of compressed files.
+func (Ω compression) extension() string {
+	switch Ω {
+	case compressionGzip:
+		return ".gz"
+	case compressionZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+func (Ω compression) writer(w io.Writer) (io.WriteCloser, error) {
+	switch Ω {
+	case compressionGzip:
+		return gzip.NewWriter(w), nil
+	case compressionZstd:
+		z, err := zstd.NewWriter(w)
+		return z, errors.Wrap(err, "could not create zstd writer")
+	}
+	return nopCloser{w}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Formats that name their files in load scripts, or write a database, cannot compress them, and
+// formats that write more than one file of data cannot stream to stdout.
+var (
+	uncompressedFormats = map[string]bool{"neo4j": true, "search": true, "sqlite": true}
+	unstreamedFormats   = map[string]bool{"neo4j": true, "search": true, "sqlite": true, "timeline": true}
+)
+
+// outputFile is written under a temporary name, which is renamed to its path if the run succeeds,
+// so that a failed run never leaves a partial file under the name of a complete one.
+type outputFile struct {
+	name, path, temp string
+}
+
+// outputs tracks the files opened for a run so they can be closed together.
+type outputs struct {
+	directory    string
+	compression  compression
+	schemaConfig *parse.SchemaConfig
+	nodes        parse.NodeMode
+	shards       shards
+	// deterministic sorts the lines of rdf output, so that identical runs write identical files.
+	deterministic bool
+	closers       []func() error
+	files         []outputFile
+	// stdout counts and hashes the file streamed to stdout, if any.
+	stdout *hashWriter
+}
+
+func newOutputs(format, directory string, c compression, deterministic bool) (*outputs, error) {
+	if deterministic && format != "rdf" {
+		return nil, errors.Errorf("format [%s] cannot be deterministic, expected rdf", format)
+	}
+	if format == "sqlite" && !sink.SQLiteSupported {
+		return nil, errors.New("format [sqlite] is not compiled in, since it needs cgo; rebuild with CGO_ENABLED=1")
+	}
+	if c != compressionNone && uncompressedFormats[format] {
+		return nil, errors.Errorf("format [%s] does not support compression", format)
+	}
+	if directory == stdoutDirectory && unstreamedFormats[format] {
+		return nil, errors.Errorf("format [%s] cannot be written to stdout", format)
+	}
+	return &outputs{directory: directory, compression: c, deterministic: deterministic}, nil
+}
+
+// dir is the directory files are written to, which is the working directory when streaming.
+func (Ω *outputs) dir() string {
+	if Ω.directory == stdoutDirectory {
+		return "."
+	}
+	return Ω.directory
+}
+
+// path registers the named file, and returns the temporary path to write it to.
+func (Ω *outputs) path(name string) string {
+	if Ω.directory == stdoutDirectory {
+		logrus.Infof("writing %s to the working directory", name)
+	}
+	f := outputFile{
+		name: name,
+		path: filepath.Join(Ω.dir(), name),
+		temp: filepath.Join(Ω.dir(), "."+name+".tmp"),
+	}
+	Ω.files = append(Ω.files, f)
+	return f.temp
+}
+
+// create opens the named file, with the extension of its compression. When writing to stdout, the
+// first file is the main output of the format and is streamed, and any others, such as the schema,
+// are written to the working directory.
+func (Ω *outputs) create(name string) (io.Writer, error) {
+	return Ω.open(name)
+}
+
+// open opens the named file as create does, returning a writer that can be closed before the other
+// files, such as a shard once it is written. Close closes it if it has not been already.
+func (Ω *outputs) open(name string) (io.WriteCloser, error) {
+	name += Ω.compression.extension()
+
+	var f io.WriteCloser
+	if Ω.directory == stdoutDirectory && Ω.stdout == nil {
+		Ω.stdout = newHashWriter(os.Stdout)
+		f = nopCloser{Ω.stdout}
+	} else {
+		filePath := Ω.path(name)
+		var err error
+		if f, err = os.Create(filePath); err != nil {
+			return nil, errors.Wrapf(err, "could not create file %s", filePath)
+		}
+		// Scripts, such as the import.sh of the neo4j format, are written executable.
+		if strings.HasSuffix(name, ".sh") {
+			if err := os.Chmod(filePath, 0755); err != nil {
+				f.Close()
+				return nil, errors.Wrapf(err, "could not make %s executable", filePath)
+			}
+		}
+	}
+
+	compressed, err := Ω.compression.writer(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	buffered := bufio.NewWriter(compressed)
+	closed := false
+	closer := func() error {
+		if closed {
+			return nil
+		}
+		closed = true
+		err := buffered.Flush()
+		if closeErr := compressed.Close(); err == nil {
+			err = closeErr
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return errors.Wrapf(err, "could not close %s", name)
+	}
+	Ω.closers = append(Ω.closers, closer)
+	return &outputWriter{Writer: buffered, close: closer}, nil
+}
+
+// outputWriter is a buffered output file.
+type outputWriter struct {
+	*bufio.Writer
+	close func() error
+}
+
+func (Ω *outputWriter) Close() error {
+	return Ω.close()
+}
+
+// Close closes every file, and returns the first error.
+func (Ω *outputs) Close() error {
+	var res error
+	for _, closer := range Ω.closers {
+		if err := closer(); err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
+
+// commit records the size and checksum of each closed file in the manifest, renames the files to
+// their paths, and then writes the manifest, so that a manifest is only present for a complete run.
+// The manifest of a previous run is removed first, so that it never describes a mix of files. If
+// a rename fails, the files not yet renamed are removed.
+func (Ω *outputs) commit(m *manifest) error {
+	for _, f := range Ω.files {
+		file, err := checksumFile(f.name, f.temp)
+		if err != nil {
+			Ω.abort()
+			return err
+		}
+		m.Files = append(m.Files, file)
+	}
+	if Ω.stdout != nil {
+		m.Files = append(m.Files, Ω.stdout.file(stdoutDirectory))
+	}
+	previous := filepath.Join(Ω.dir(), manifestName)
+	if err := os.Remove(previous); err != nil && !os.IsNotExist(err) {
+		Ω.abort()
+		return errors.Wrapf(err, "could not remove %s", previous)
+	}
+	for _, f := range Ω.files {
+		if err := os.Rename(f.temp, f.path); err != nil {
+			Ω.abort()
+			return errors.Wrapf(err, "could not rename %s to %s", f.temp, f.path)
+		}
+	}
+	return m.write(Ω.dir())
+}
+
+// abort removes the temporary files of a failed run, and any not yet renamed by commit.
+func (Ω *outputs) abort() {
+	for _, f := range Ω.files {
+		if err := os.Remove(f.temp); err != nil && !os.IsNotExist(err) {
+			logrus.Warnf("could not remove %s: %v", f.temp, err)
+		}
+	}
+}
+
+// newSink opens the files of the output format and creates its sink.
+func newSink(format string, o *outputs) (parse.Sink, error) {
+	if o.shards.enabled() {
+		return newShardedSink(format, o)
+	}
+	switch format {
+	case "rdf":
+		rdfWriter, err := o.create("wikivents.nt")
+		if err != nil {
+			return nil, err
+		}
+		schemaWriter, err := o.create("wikivents.schema")
+		if err != nil {
+			return nil, err
+		}
+		return dgraphSink(format, rdfWriter, parse.NewDgraphSchema(schemaWriter, o.schemaConfig), o.nodes, o.deterministic), nil
+	case "dgraph-upsert":
+		upsertWriter, err := o.create("wikivents.upsert")
+		if err != nil {
+			return nil, err
+		}
+		schemaWriter, err := o.create("wikivents.schema")
+		if err != nil {
+			return nil, err
+		}
+		return parse.NewUpsertSink(upsertWriter, parse.NewDgraphSchema(schemaWriter, o.schemaConfig), sink.DefaultChunkSize), nil
+	case "neo4j":
+		return sink.NewNeo4jCSV(o.create), nil
+	case "sqlite":
+		// Replace the database of a failed run, since the database must not exist yet.
+		filePath := o.path("wikivents.db")
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "could not remove %s", filePath)
+		}
+		return sink.NewSQLite(filePath)
+	case "geojson":
+		w, err := o.create("wikivents.geojson")
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewGeoJSON(w), nil
+	case "kml":
+		w, err := o.create("wikivents.kml")
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewKML(w), nil
+	case "documents":
+		w, err := o.create("wikivents.documents.ndjson")
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewDocuments(w), nil
+	case "chronology":
+		w, err := o.create("wikivents.chronology.ndjson")
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewChronology(w, extsort.DefaultChunkSize), nil
+	case "timeline":
+		return sink.NewTimeline(o.create), nil
+	case "gexf":
+		w, err := o.create("wikivents.gexf")
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewGEXF(w), nil
+	case "graphml":
+		w, err := o.create("wikivents.graphml")
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewGraphML(w), nil
+	case "search":
+		return sink.NewSearchBulk(o.create, sink.DefaultBulkSize), nil
+	case "turtle":
+		w, err := o.create("wikivents.ttl")
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewTurtle(w, namespace), nil
+	case "jsonld":
+		w, err := o.create("wikivents.jsonld")
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewJSONLD(w, namespace), nil
+	case "dgraph-json":
+		jsonWriter, err := o.create("wikivents.json")
+		if err != nil {
+			return nil, err
+		}
+		schemaWriter, err := o.create("wikivents.schema")
+		if err != nil {
+			return nil, err
+		}
+		return sink.NewDgraphJSON(jsonWriter, parse.NewDgraphSchema(schemaWriter, o.schemaConfig), sink.DefaultChunkSize), nil
+	default:
+		return nil, errors.Errorf("unknown format [%s], expected rdf, dgraph-json, dgraph-upsert, neo4j, sqlite, geojson, kml, search, documents, chronology, timeline, gexf, graphml, turtle or jsonld", format)
+	}
+}
+
+func readSchemaConfig(filePath string) (*parse.SchemaConfig, error) {
+	if filePath == "" {
+		return nil, nil
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open schema config %s", filePath)
+	}
+	defer f.Close()
+	return parse.ReadSchemaConfig(f)
+}
@@ -8,11 +8,12 @@ package cmd
 import (
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/heindl/wikivents/fetch"
-	"github.com/pkg/errors"
+	"github.com/heindl/wikivents/fetch/endpoint"
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/heindl/wikivents/fetch/sink"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -31,13 +32,18 @@ var rootCmd = &cobra.Command{
 		- 'f_': a feature with a descriptive literal value.
 		- 't_': a type with an empty default value.
 		- 'e_': a edge to the uid of another node.
+
+	Other outputs are chosen with '--format'. Files are renamed into place once the run succeeds, along with a manifest.json that records how they were made.
 	`,
 	Example: fmt.Sprintf(`
-		$ %s -o /tmp/ -s -70 -e 300
+		$ %s -o /tmp/ -s -70 -e 300 --compression gzip
 		$ ls /tmp
 		wikivents.schema.gz		wikivents.nt.gz
-		$ dgraph live -s /tmp/wikivents.schema.gz -r /tmp/wikivents.nt.gz -z 127.0.0.1:5080
-	`, commandName),
+		$ dgraph live -s /tmp/wikivents.schema.gz -f /tmp/wikivents.nt.gz -z 127.0.0.1:5080
+
+		$ %s -o - -s -70 -e 300 | gzip > /tmp/wikivents.nt.gz
+		$ dgraph live -s wikivents.schema -f /tmp/wikivents.nt.gz -z 127.0.0.1:5080
+	`, commandName, commandName),
 	RunE: process,
 }
 
@@ -46,12 +52,55 @@ var verbose bool
 var outputDirectory string
 var startYear int
 var endYear int
+var references string
+var languages []string
+var format string
+var namespace string
+var schemaConfig string
+var schemaConflicts string
+var predicates string
+var predicateDictionary bool
+var nodes string
+var compressionName string
+var shardBy string
+var shardSize string
+var shardSchemaName string
+var deterministic bool
+var dates bool
 
 func init() {
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "print debug information")
-	rootCmd.Flags().StringVarP(&outputDirectory, "output-directory", "o", ".", "directory path to write compressed RDF files")
+	rootCmd.Flags().StringVarP(&outputDirectory, "output-directory", "o", ".", "directory path to write output files, or '-' to stream the main file to stdout and write any others, such as the schema, to the working directory once the run succeeds")
+	rootCmd.Flags().StringVar(&compressionName, "compression", "none", "compress output files with 'gzip' or 'zstd', adding a .gz or .zst extension")
+	rootCmd.Flags().StringVar(&shardBy, "shard-by", "", "split rdf or dgraph-json data into files by the start year of each entity, such as 'year:100' for wikivents.1000_1099.nt")
+	rootCmd.Flags().StringVar(&shardSize, "shard-size", "", "split rdf or dgraph-json data into numbered files of at most a size, such as '1GB' for wikivents.0001.nt; load shards into one graph with '--nodes iri' or '--nodes xid'")
+	rootCmd.Flags().StringVar(&shardSchemaName, "shard-schema", "shared", "write one 'shared' schema for sharded data, or a schema for each 'shard'")
+	rootCmd.Flags().BoolVar(&deterministic, "deterministic", false, "sort rdf lines, so that identical runs write identical files")
 	rootCmd.Flags().IntVarP(&startYear, "start-year", "s", 0, "start year for query range")
 	rootCmd.Flags().IntVarP(&endYear, "end-year", "e", 0, "end year for query range")
+	rootCmd.Flags().StringVar(&references, "references", "none", "write statement references (stated in, reference URL, retrieved) as 'facets' on each edge, which keeps only the first reference of each value, or as reference 'nodes', which keeps them all")
+	rootCmd.Flags().StringSliceVar(&languages, "languages", nil, "label languages in fallback order, each also written as a language tagged label (default en)")
+	rootCmd.Flags().StringVar(&format, "format", "rdf", `output format:
+  'rdf': Dgraph N-Triples and a schema with a type for each 't_' class
+  'dgraph-json': line delimited Dgraph JSON mutations and schema, for 'dgraph live -f'
+  'dgraph-upsert': Dgraph upsert blocks that match each node on its 'xid', for the /mutate endpoint
+  'neo4j': neo4j-admin import CSV files for each label and relationship, and an import.sh script
+  'sqlite': a SQLite database of entities, types, features and edges, with views such as events_by_year
+  'geojson', 'kml': every located entity as a map feature, for QGIS or Google Earth
+  'search': Elasticsearch or OpenSearch _bulk files of at most 50MB, the index mapping, and a load.sh script
+  'documents': a nested JSON document per line for every entity, sorted by start year
+  'chronology': a JSON event per line in date order, sorted on disk
+  'timeline': TimelineJS3 and vis-timeline JSON, grouped by type
+  'gexf', 'graphml': the graph for network analysis, with the years of each node
+  'turtle', 'jsonld': standard RDF with Wikidata IRIs and predicates in '--namespace'
+`)
+	rootCmd.Flags().StringVar(&nodes, "nodes", "blank", "identify nodes in rdf output as 'blank' nodes, which are new on every load, Wikidata 'iri's, which merge repeated loads with a persistent xidmap, or blank nodes with an 'xid' predicate holding the IRI")
+	rootCmd.Flags().StringVar(&namespace, "namespace", sink.DefaultNamespace, "IRI namespace of the f_, e_ and t_ predicates in turtle and jsonld output")
+	rootCmd.Flags().StringVar(&schemaConfig, "schema-config", "", "JSON file of index, @reverse and @count directives per predicate for the Dgraph schema")
+	rootCmd.Flags().StringVar(&schemaConflicts, "schema-conflicts", "widen", "resolve a predicate with values of more than one type by writing it and its values as one wider type ('widen'), or by keeping the lowest sorted type and writing the others to predicates suffixed by type ('split'); either way, values are held on disk until the run ends, when their types are final")
+	rootCmd.Flags().StringVar(&predicates, "predicates", "label", "name property predicates by English 'label' (e_participant) or by property 'id' (e_P710), which stays stable when labels change")
+	rootCmd.Flags().BoolVar(&dates, "dates", false, "write the full date and precision of each time value as facets alongside its year; always on for chronology and timeline output")
+	rootCmd.Flags().BoolVar(&predicateDictionary, "predicate-dictionary", false, "write wikivents.predicates.json, mapping each predicate to its property ID, label and datatype")
 }
 
 func process(cmd *cobra.Command, args []string) (resErr error) {
@@ -60,50 +109,109 @@ func process(cmd *cobra.Command, args []string) (resErr error) {
 		logrus.SetFormatter(&logrus.JSONFormatter{})
 	}
 
-	rdfWriter, rdfCloser, err := gZipWriter(filepath.Join(outputDirectory, "wikivents.nt"))
+	referenceMode, err := parse.NewReferenceMode(references)
 	if err != nil {
 		return err
 	}
+
+	conflictMode, err := parse.NewConflictMode(schemaConflicts)
+	if err != nil {
+		return err
+	}
+
+	predicateMode, err := parse.NewPredicateMode(predicates)
+	if err != nil {
+		return err
+	}
+
+	nodeMode, err := parse.NewNodeMode(nodes)
+	if err != nil {
+		return err
+	}
+
+	config, err := readSchemaConfig(schemaConfig)
+	if err != nil {
+		return err
+	}
+
+	c, err := newCompression(compressionName)
+	if err != nil {
+		return err
+	}
+
+	o, err := newOutputs(format, outputDirectory, c, deterministic)
+	if err != nil {
+		return err
+	}
+	if o.shards, err = newShards(format, outputDirectory, shardBy, shardSize, shardSchemaName); err != nil {
+		return err
+	}
+	o.schemaConfig, o.nodes = config, nodeMode
+
+	templates, err := endpoint.Templates()
+	if err != nil {
+		return err
+	}
+	m := &manifest{
+		StartYear:      startYear,
+		EndYear:        endYear,
+		Format:         format,
+		Compression:    c,
+		References:     references,
+		Languages:      languages,
+		PredicateNames: predicates,
+		ShardBy:        shardBy,
+		ShardSize:      shardSize,
+		Deterministic:  deterministic,
+		Dates:          dates || format == "chronology" || format == "timeline",
+		Endpoint:       endpoint.WikidataEndpoint,
+		Templates:      templates,
+		Started:        time.Now().UTC(),
+	}
+
+	// Files are renamed into place with the manifest only if the whole run succeeds.
 	defer func() {
-		if closeErr := rdfCloser(); closeErr != nil && resErr == nil {
+		// Only a clean return commits the files, so a panic removes them before it continues.
+		if r := recover(); r != nil {
+			o.Close()
+			o.abort()
+			panic(r)
+		}
+		if closeErr := o.Close(); closeErr != nil && resErr == nil {
 			resErr = closeErr
 		}
+		if resErr != nil {
+			o.abort()
+			return
+		}
+		m.Finished = time.Now().UTC()
+		resErr = o.commit(m)
 	}()
 
-	schemaWriter, schemaCloser, err := gZipWriter(filepath.Join(outputDirectory, "wikivents.schema"))
+	s, err := newSink(format, o)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if closeErr := schemaCloser(); closeErr != nil && resErr == nil {
-			resErr = closeErr
+
+	var dictionary io.Writer
+	if predicateDictionary {
+		if dictionary, err = o.create("wikivents.predicates.json"); err != nil {
+			return err
 		}
-	}()
+	}
 
-	return fetch.WikidataEvents(startYear, endYear, rdfWriter, schemaWriter)
+	m.Stats, err = fetch.WikidataEvents(startYear, endYear, fetch.Options{
+		References: referenceMode,
+		Languages:  languages,
+		Conflicts:  conflictMode,
+		Predicates: predicateMode,
+		Dictionary: dictionary,
+		Dates:      m.Dates,
+	}, s)
+	return err
 
 }
 
 func Execute() {
 	rootCmd.Execute()
 }
-
-func gZipWriter(filePath string) (io.Writer, func() error, error) {
-
-	f, err := os.Create(filePath)
-	if err != nil {
-		return nil, nil, errors.Wrapf(err, "could not create file %s", filePath)
-	}
-
-	//g := gzip.NewWriter(f)
-
-	return f, func() error {
-		//if err := g.Close(); err != nil {
-		//	return errors.Wrap(err, "could not close gzip")
-		//}
-		if err := f.Close(); err != nil {
-			return errors.Wrapf(err, "could not close file %s", filePath)
-		}
-		return nil
-	}, nil
-}
@@ -0,0 +1,250 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/heindl/wikivents/fetch/extsort"
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/heindl/wikivents/fetch/sink"
+	"github.com/pkg/errors"
+)
+
+// shardSchema is whether sharded output has one schema file or a schema file for each shard.
+type shardSchema string
+
+const (
+	shardSchemaShared = shardSchema("shared")
+	shardSchemaShard  = shardSchema("shard")
+)
+
+// shards splits the data file of a Dgraph format by the start year of each entity, or by size.
+type shards struct {
+	years  int
+	size   int64
+	schema shardSchema
+}
+
+// dgraphExtensions are the extensions of the data files of the formats that can be sharded.
+var dgraphExtensions = map[string]string{"rdf": "nt", "dgraph-json": "json"}
+
+func newShards(format, directory, by, size, schema string) (shards, error) {
+	res := shards{schema: shardSchema(schema)}
+	if res.schema != shardSchemaShared && res.schema != shardSchemaShard {
+		return res, errors.Errorf("unknown shard schema [%s], expected shared or shard", schema)
+	}
+	if by != "" {
+		years := strings.TrimPrefix(by, "year:")
+		n, err := strconv.Atoi(years)
+		if years == by || err != nil || n <= 0 {
+			return res, errors.Errorf("invalid shard [%s], expected year:N for spans of N years", by)
+		}
+		res.years = n
+	}
+	if size != "" {
+		n, err := parseSize(size)
+		if err != nil {
+			return res, err
+		}
+		res.size = n
+	}
+	if !res.enabled() {
+		return res, nil
+	}
+	if res.years > 0 && res.size > 0 {
+		return res, errors.New("shard by year or by size, but not both")
+	}
+	if _, ok := dgraphExtensions[format]; !ok {
+		return res, errors.Errorf("format [%s] cannot be sharded, expected rdf or dgraph-json", format)
+	}
+	if directory == stdoutDirectory {
+		return res, errors.New("sharded output cannot be written to stdout")
+	}
+	return res, nil
+}
+
+func (Ω shards) enabled() bool {
+	return Ω.years > 0 || Ω.size > 0
+}
+
+// sizeUnits are 1024 based, as file sizes usually are.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize reads a size such as 1GB or 500MB.
+func parseSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	for _, unit := range sizeUnits {
+		if !strings.HasSuffix(upper, unit.suffix) {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix)), 10, 64)
+		if err != nil || n <= 0 {
+			break
+		}
+		return n * unit.bytes, nil
+	}
+	return 0, errors.Errorf("invalid shard size [%s], expected a size such as 500MB or 1GB", s)
+}
+
+// dgraphSink creates the sink of a Dgraph format, which writes its data to the writer, sorted if
+// the rdf is to be deterministic.
+func dgraphSink(format string, w io.Writer, schema *parse.DgraphSchema, nodes parse.NodeMode, sorted bool) parse.Sink {
+	if format == "dgraph-json" {
+		return sink.NewDgraphJSON(w, schema, sink.DefaultChunkSize)
+	}
+	if sorted {
+		return parse.NewSortedRDFSink(w, schema, nodes, extsort.DefaultChunkSize)
+	}
+	return parse.NewRDFSink(w, schema, nodes)
+}
+
+// newShardedSink writes the data of a Dgraph format to numbered files of at most the shard size, or
+// to files named by the span of years their entities start in.
+func newShardedSink(format string, o *outputs) (parse.Sink, error) {
+	extension := dgraphExtensions[format]
+
+	if o.shards.size > 0 {
+		data := newShardWriter(o.shards.size, func(shard int) (io.Writer, error) {
+			return o.create(fmt.Sprintf("wikivents.%04d.%s", shard, extension))
+		})
+		var schemaWriter io.Writer = &shardSchemaWriter{data: data, create: o.create}
+		if o.shards.schema == shardSchemaShared {
+			var err error
+			if schemaWriter, err = o.create("wikivents.schema"); err != nil {
+				return nil, err
+			}
+		}
+		return dgraphSink(format, data, parse.NewDgraphSchema(schemaWriter, o.schemaConfig), o.nodes, o.deterministic), nil
+	}
+
+	// Shards are written one at a time, and their files closed along with their sinks.
+	open := func(shard string) (parse.Sink, error) {
+		w, err := o.open(fmt.Sprintf("wikivents.%s.%s", shard, extension))
+		if err != nil {
+			return nil, err
+		}
+		files := []io.Closer{w}
+		schemaWriter := ioutil.Discard
+		if o.shards.schema == shardSchemaShard {
+			f, err := o.open(fmt.Sprintf("wikivents.%s.schema", shard))
+			if err != nil {
+				return nil, err
+			}
+			schemaWriter, files = f, append(files, f)
+		}
+		return &closingSink{
+			Sink:  dgraphSink(format, w, parse.NewDgraphSchema(schemaWriter, o.schemaConfig), o.nodes, o.deterministic),
+			files: files,
+		}, nil
+	}
+	var shared parse.Sink
+	if o.shards.schema == shardSchemaShared {
+		schemaWriter, err := o.create("wikivents.schema")
+		if err != nil {
+			return nil, err
+		}
+		shared = dgraphSink(format, ioutil.Discard, parse.NewDgraphSchema(schemaWriter, o.schemaConfig), o.nodes, false)
+	}
+	return sink.NewYearShards(o.shards.years, open, shared, extsort.DefaultChunkSize), nil
+}
+
+// closingSink closes the files of a sink once the sink is closed.
+type closingSink struct {
+	parse.Sink
+	files []io.Closer
+}
+
+func (Ω *closingSink) Abort() {
+	if a, ok := Ω.Sink.(parse.Aborter); ok {
+		a.Abort()
+	}
+}
+
+func (Ω *closingSink) Close() error {
+	if err := Ω.Sink.Close(); err != nil {
+		return err
+	}
+	for _, f := range Ω.files {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardWriter writes lines to numbered shards, and starts the next shard before a line that would
+// take the current one past its size. Every write must be whole lines.
+type shardWriter struct {
+	sync.Mutex
+	size    int64
+	create  func(shard int) (io.Writer, error)
+	shards  int
+	written int64
+	current io.Writer
+}
+
+func newShardWriter(size int64, create func(shard int) (io.Writer, error)) *shardWriter {
+	return &shardWriter{size: size, create: create}
+}
+
+func (Ω *shardWriter) Write(p []byte) (int, error) {
+	Ω.Lock()
+	defer Ω.Unlock()
+	if Ω.current == nil || (Ω.written > 0 && Ω.written+int64(len(p)) > Ω.size) {
+		w, err := Ω.create(Ω.shards + 1)
+		if err != nil {
+			return 0, err
+		}
+		Ω.current, Ω.shards, Ω.written = w, Ω.shards+1, 0
+	}
+	n, err := Ω.current.Write(p)
+	Ω.written += int64(n)
+	return n, err
+}
+
+// count returns the number of shards written.
+func (Ω *shardWriter) count() int {
+	Ω.Lock()
+	defer Ω.Unlock()
+	return Ω.shards
+}
+
+// shardSchemaWriter copies the schema to a file for each shard of the data, which are all written
+// by the time the schema is.
+type shardSchemaWriter struct {
+	data    *shardWriter
+	create  func(name string) (io.Writer, error)
+	writers io.Writer
+}
+
+func (Ω *shardSchemaWriter) Write(p []byte) (int, error) {
+	if Ω.writers == nil {
+		writers := []io.Writer{}
+		for shard := 1; shard <= Ω.data.count(); shard++ {
+			w, err := Ω.create(fmt.Sprintf("wikivents.%04d.schema", shard))
+			if err != nil {
+				return 0, err
+			}
+			writers = append(writers, w)
+		}
+		Ω.writers = io.MultiWriter(writers...)
+	}
+	return Ω.writers.Write(p)
+}
@@ -107,6 +107,14 @@ func (b Binding) MustString(key string) (string, error) {
 	return b[key].Value, nil
 }
 
+// Lang returns the xml:lang tag of a literal value, if any.
+func (b Binding) Lang(key string) string {
+	if err := b.ensureKey(key); err != nil {
+		return ""
+	}
+	return b[key].Lang
+}
+
 func (b Binding) Type(key string) string {
 	if err := b.ensureKey(key); err != nil {
 		return ""
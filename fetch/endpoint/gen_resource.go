@@ -14,131 +14,213 @@ import (
 
 func init() {
 	parcello.AddResource([]byte{
-		80, 75, 3, 4, 20, 0, 8, 0, 8, 0, 162, 137, 36, 77, 0, 0, 0,
+		80, 75, 3, 4, 20, 0, 8, 0, 8, 0, 0, 13, 110, 77, 0, 0, 0,
 		0, 0, 0, 0, 0, 0, 0, 0, 0, 28, 0, 9, 0, 115, 112, 97, 114,
 		113, 108, 47, 100, 97, 116, 101, 100, 45, 101, 110, 116, 105,
 		116, 105, 101, 115, 46, 115, 112, 97, 114, 113, 108, 85, 84,
-		5, 0, 1, 33, 189, 142, 91, 100, 145, 65, 79, 242, 64, 16,
-		134, 239, 253, 21, 111, 56, 144, 246, 66, 232, 71, 248, 208,
-		70, 109, 16, 87, 37, 33, 82, 75, 213, 112, 50, 67, 59, 226,
-		70, 216, 146, 221, 69, 68, 220, 255, 110, 10, 66, 136, 222,
-		158, 228, 121, 51, 147, 119, 102, 36, 6, 162, 151, 33, 150,
-		202, 88, 82, 57, 15, 95, 6, 52, 225, 25, 252, 169, 46, 151,
-		139, 231, 188, 84, 57, 89, 63, 230, 0, 100, 16, 179, 178,
-		210, 74, 54, 1, 158, 110, 69, 42, 176, 241, 128, 152, 225,
-		175, 10, 27, 37, 237, 147, 54, 190, 240, 131, 205, 3, 254,
-		63, 61, 96, 39, 220, 99, 216, 10, 59, 91, 142, 146, 127, 205,
-		86, 24, 32, 46, 200, 114, 195, 3, 174, 251, 131, 76, 164,
-		254, 85, 55, 235, 102, 227, 68, 248, 91, 17, 224, 28, 31,
-		166, 136, 42, 206, 228, 156, 81, 175, 99, 205, 164, 247, 246,
-		2, 155, 77, 99, 204, 164, 71, 150, 180, 117, 238, 183, 63,
-		219, 123, 161, 10, 231, 130, 106, 207, 72, 164, 143, 253,
-		158, 192, 74, 190, 201, 9, 25, 142, 102, 219, 222, 85, 35,
-		96, 82, 68, 134, 245, 187, 204, 57, 33, 77, 243, 227, 144,
-		154, 46, 105, 202, 168, 177, 170, 161, 154, 227, 60, 224,
-		85, 42, 27, 221, 47, 89, 175, 119, 88, 46, 172, 156, 203,
-		79, 214, 168, 221, 149, 138, 119, 193, 152, 119, 213, 91,
-		225, 241, 181, 209, 240, 156, 119, 147, 14, 31, 18, 92, 142,
-		255, 188, 225, 59, 0, 0, 255, 255, 80, 75, 7, 8, 144, 180,
-		211, 170, 19, 1, 0, 0, 158, 1, 0, 0, 80, 75, 3, 4, 20, 0,
-		8, 0, 8, 0, 169, 145, 36, 77, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-		0, 0, 20, 0, 9, 0, 115, 112, 97, 114, 113, 108, 47, 101, 110,
-		116, 105, 116, 121, 46, 115, 112, 97, 114, 113, 108, 85, 84,
-		5, 0, 1, 63, 203, 142, 91, 116, 145, 79, 111, 226, 48, 16,
-		197, 239, 249, 20, 179, 57, 32, 184, 68, 90, 237, 45, 218,
-		22, 161, 224, 170, 145, 82, 160, 36, 165, 103, 39, 25, 168,
-		219, 196, 142, 28, 3, 165, 150, 191, 123, 149, 63, 110, 160,
-		136, 147, 237, 151, 223, 188, 121, 122, 137, 73, 68, 130,
-		4, 230, 97, 156, 132, 139, 32, 129, 169, 72, 223, 49, 83,
-		246, 140, 104, 138, 133, 125, 204, 177, 206, 36, 171, 20,
-		19, 220, 74, 33, 175, 21, 229, 25, 46, 183, 61, 89, 73, 81,
-		161, 84, 167, 254, 121, 100, 31, 44, 165, 53, 38, 167, 10,
-		97, 122, 160, 197, 222, 30, 61, 208, 222, 47, 140, 91, 101,
-		240, 189, 18, 186, 193, 215, 71, 178, 38, 160, 29, 128, 205,
-		44, 122, 33, 49, 140, 251, 68, 147, 86, 4, 208, 26, 36, 229,
-		59, 4, 143, 112, 197, 20, 195, 26, 140, 209, 26, 216, 22,
-		60, 48, 102, 252, 95, 107, 207, 152, 251, 137, 214, 128, 60,
-		239, 190, 33, 207, 141, 113, 0, 12, 120, 14, 12, 85, 84, 54,
-		249, 185, 122, 204, 149, 191, 250, 247, 247, 186, 136, 6,
-		122, 99, 92, 249, 207, 123, 148, 167, 238, 42, 42, 197, 74,
-		246, 133, 18, 220, 133, 224, 232, 118, 78, 182, 43, 176, 45,
-		249, 57, 147, 152, 169, 160, 160, 172, 108, 214, 222, 160,
-		172, 210, 149, 122, 81, 113, 51, 177, 92, 37, 225, 114, 49,
-		139, 116, 159, 250, 39, 233, 175, 34, 77, 3, 63, 132, 81,
-		66, 214, 227, 75, 151, 63, 119, 195, 50, 242, 169, 80, 114,
-		90, 132, 57, 140, 70, 112, 155, 11, 68, 89, 10, 94, 63, 97,
-		206, 232, 164, 205, 17, 147, 245, 38, 12, 200, 128, 20, 237,
-		159, 211, 144, 230, 126, 141, 242, 192, 50, 92, 81, 73, 203,
-		115, 128, 239, 246, 116, 135, 224, 34, 119, 193, 56, 230,
-		59, 0, 0, 255, 255, 80, 75, 7, 8, 26, 32, 153, 95, 80, 1,
-		0, 0, 158, 2, 0, 0, 80, 75, 3, 4, 20, 0, 8, 0, 8, 0, 53, 118,
-		35, 77, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 31, 0, 9, 0, 115,
-		112, 97, 114, 113, 108, 47, 101, 118, 101, 110, 116, 45, 112,
-		97, 114, 116, 105, 99, 105, 112, 97, 110, 116, 46, 115, 112,
-		97, 114, 113, 108, 85, 84, 5, 0, 1, 7, 74, 141, 91, 124, 144,
-		65, 75, 244, 48, 16, 134, 239, 253, 21, 97, 191, 123, 242,
-		45, 30, 132, 34, 187, 135, 53, 98, 97, 15, 165, 43, 234, 117,
-		210, 140, 49, 216, 77, 66, 58, 110, 41, 165, 255, 93, 154,
-		22, 108, 47, 230, 148, 12, 207, 251, 188, 97, 202, 74, 62,
-		21, 239, 76, 233, 156, 61, 124, 18, 133, 92, 136, 174, 235,
-		184, 178, 70, 3, 1, 175, 253, 85, 68, 253, 241, 239, 144,
-		45, 96, 103, 191, 172, 130, 22, 87, 120, 154, 240, 22, 133,
-		119, 228, 27, 111, 250, 21, 173, 105, 235, 157, 224, 36, 246,
-		209, 136, 16, 125, 16, 218, 70, 172, 73, 172, 34, 127, 36,
-		208, 145, 165, 94, 28, 178, 139, 60, 203, 211, 11, 59, 6,
-		136, 100, 107, 27, 192, 209, 230, 113, 6, 133, 13, 59, 82,
-		31, 112, 190, 190, 61, 203, 74, 178, 33, 99, 140, 77, 21,
-		195, 192, 229, 13, 29, 21, 143, 227, 152, 126, 89, 222, 239,
-		255, 111, 117, 60, 177, 155, 81, 2, 239, 246, 179, 119, 1,
-		46, 178, 122, 45, 78, 242, 119, 49, 77, 234, 155, 155, 166,
-		163, 116, 222, 98, 188, 217, 26, 75, 136, 112, 93, 131, 206,
-		124, 131, 65, 182, 67, 183, 91, 108, 99, 54, 254, 4, 0, 0,
-		255, 255, 80, 75, 7, 8, 135, 192, 250, 145, 227, 0, 0, 0,
-		146, 1, 0, 0, 80, 75, 3, 4, 20, 0, 8, 0, 8, 0, 151, 169, 33,
-		77, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 18, 0, 9, 0, 115,
-		112, 97, 114, 113, 108, 47, 116, 101, 115, 116, 46, 115, 112,
-		97, 114, 113, 108, 85, 84, 5, 0, 1, 207, 0, 139, 91, 124,
-		143, 81, 75, 195, 48, 20, 133, 223, 251, 43, 46, 235, 123,
-		99, 145, 234, 40, 178, 61, 212, 136, 129, 41, 181, 45, 234,
-		107, 218, 92, 99, 88, 151, 148, 244, 186, 50, 100, 255, 93,
-		86, 203, 44, 10, 230, 49, 231, 59, 31, 231, 230, 5, 191, 19,
-		175, 48, 168, 20, 110, 222, 137, 186, 148, 177, 97, 24, 162,
-		193, 108, 141, 146, 36, 35, 231, 53, 67, 75, 134, 14, 108,
-		21, 156, 97, 250, 135, 238, 188, 235, 152, 50, 30, 27, 154,
-		85, 204, 214, 212, 178, 199, 89, 111, 252, 137, 122, 100,
-		206, 146, 107, 157, 62, 132, 103, 186, 254, 181, 166, 54,
-		122, 212, 55, 110, 199, 188, 122, 11, 87, 65, 8, 132, 61,
-		25, 171, 131, 146, 111, 120, 86, 193, 173, 40, 43, 241, 152,
-		85, 176, 86, 146, 16, 94, 238, 121, 193, 225, 51, 0, 0, 88,
-		227, 30, 45, 141, 171, 243, 203, 248, 116, 234, 83, 124, 189,
-		76, 174, 98, 136, 254, 228, 201, 50, 153, 12, 223, 89, 201,
-		139, 103, 145, 241, 159, 253, 173, 172, 177, 157, 196, 167,
-		87, 171, 180, 71, 191, 55, 13, 230, 210, 203, 221, 28, 180,
-		250, 67, 106, 132, 5, 218, 197, 100, 59, 6, 71, 216, 136,
-		7, 81, 65, 124, 241, 21, 0, 0, 255, 255, 80, 75, 7, 8, 204,
-		170, 159, 25, 231, 0, 0, 0, 121, 1, 0, 0, 80, 75, 1, 2, 20,
-		3, 20, 0, 8, 0, 8, 0, 162, 137, 36, 77, 144, 180, 211, 170,
-		19, 1, 0, 0, 158, 1, 0, 0, 28, 0, 9, 0, 0, 0, 0, 0, 0, 0,
-		0, 0, 164, 129, 0, 0, 0, 0, 115, 112, 97, 114, 113, 108, 47,
-		100, 97, 116, 101, 100, 45, 101, 110, 116, 105, 116, 105,
-		101, 115, 46, 115, 112, 97, 114, 113, 108, 85, 84, 5, 0, 1,
-		33, 189, 142, 91, 80, 75, 1, 2, 20, 3, 20, 0, 8, 0, 8, 0,
-		169, 145, 36, 77, 26, 32, 153, 95, 80, 1, 0, 0, 158, 2, 0,
-		0, 20, 0, 9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 164, 129, 102, 1,
+		5, 0, 1, 241, 124, 235, 91, 108, 81, 81, 111, 211, 48, 24,
+		124, 207, 175, 56, 5, 41, 74, 30, 86, 197, 171, 178, 13, 171,
+		35, 26, 195, 192, 164, 138, 133, 52, 128, 250, 50, 228, 36,
+		223, 58, 139, 197, 153, 108, 151, 82, 218, 252, 119, 148,
+		132, 162, 2, 123, 242, 233, 238, 124, 223, 167, 239, 22, 98,
+		46, 174, 11, 164, 74, 91, 39, 117, 69, 183, 247, 115, 89,
+		210, 35, 194, 149, 105, 215, 79, 95, 171, 86, 87, 210, 133,
+		41, 69, 144, 22, 41, 105, 167, 156, 34, 27, 225, 203, 123,
+		145, 11, 236, 60, 32, 37, 132, 155, 218, 241, 44, 185, 72,
+		176, 199, 166, 118, 60, 75, 46, 98, 236, 49, 178, 103, 47,
+		255, 176, 231, 236, 0, 217, 148, 157, 15, 152, 103, 167, 241,
+		148, 69, 72, 107, 233, 104, 226, 1, 47, 80, 60, 40, 139, 70,
+		110, 81, 18, 238, 165, 117, 100, 248, 192, 191, 189, 153,
+		23, 34, 15, 253, 211, 152, 37, 39, 49, 59, 137, 153, 127,
+		119, 247, 195, 214, 188, 255, 90, 168, 134, 48, 187, 68, 90,
+		183, 37, 130, 96, 124, 103, 232, 205, 103, 207, 154, 163,
+		126, 216, 239, 200, 55, 87, 197, 85, 177, 204, 68, 56, 108,
+		17, 225, 18, 127, 197, 6, 1, 182, 36, 205, 65, 125, 133, 221,
+		110, 178, 36, 105, 22, 78, 26, 215, 117, 255, 234, 179, 131,
+		46, 116, 221, 117, 195, 156, 133, 200, 63, 223, 92, 11, 108,
+		212, 55, 85, 74, 75, 252, 113, 56, 114, 127, 62, 160, 172,
+		185, 37, 243, 93, 85, 148, 73, 35, 155, 99, 147, 94, 173,
+		229, 138, 224, 147, 246, 209, 231, 116, 30, 240, 160, 180,
+		227, 31, 215, 100, 182, 35, 108, 159, 156, 106, 212, 79, 50,
+		240, 63, 180, 154, 70, 99, 74, 99, 17, 83, 118, 92, 45, 38,
+		94, 231, 189, 203, 111, 63, 101, 120, 189, 252, 175, 243,
+		95, 3, 0, 80, 75, 7, 8, 113, 118, 5, 211, 78, 1, 0, 0, 11,
+		2, 0, 0, 80, 75, 3, 4, 20, 0, 8, 0, 8, 0, 60, 109, 83, 93,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 20, 0, 9, 0, 115, 112,
+		97, 114, 113, 108, 47, 101, 110, 116, 105, 116, 121, 46, 115,
+		112, 97, 114, 113, 108, 85, 84, 5, 0, 1, 36, 30, 214, 106,
+		172, 86, 77, 111, 227, 54, 16, 189, 235, 87, 76, 125, 88,
+		36, 23, 99, 243, 177, 64, 32, 180, 53, 188, 142, 22, 21, 160,
+		77, 28, 201, 73, 207, 140, 52, 73, 216, 181, 40, 149, 162,
+		237, 166, 130, 254, 123, 33, 137, 148, 248, 33, 39, 187, 197,
+		222, 164, 55, 111, 134, 51, 228, 227, 12, 147, 32, 10, 86,
+		27, 184, 14, 147, 77, 120, 179, 218, 120, 0, 0, 139, 226,
+		241, 47, 76, 69, 68, 30, 113, 171, 3, 215, 88, 165, 156, 150,
+		130, 22, 76, 135, 67, 86, 9, 194, 82, 188, 125, 210, 60, 74,
+		94, 148, 200, 197, 171, 249, 167, 17, 14, 244, 27, 125, 36,
+		21, 110, 94, 75, 236, 73, 123, 178, 221, 233, 159, 26, 185,
+		51, 57, 203, 119, 232, 184, 250, 36, 168, 5, 249, 123, 71,
+		152, 160, 226, 245, 158, 81, 225, 34, 83, 196, 178, 68, 254,
+		185, 216, 177, 204, 196, 163, 226, 48, 137, 135, 76, 224,
+		51, 242, 85, 193, 42, 193, 9, 101, 114, 21, 65, 115, 92, 115,
+		76, 105, 165, 50, 175, 107, 160, 79, 48, 143, 241, 9, 57,
+		178, 20, 43, 104, 154, 69, 37, 136, 192, 28, 149, 23, 87,
+		70, 235, 55, 105, 105, 89, 200, 44, 150, 130, 181, 50, 134,
+		8, 247, 113, 100, 33, 49, 10, 78, 113, 143, 89, 93, 3, 178,
+		12, 154, 166, 35, 156, 36, 203, 175, 235, 40, 56, 145, 7,
+		123, 10, 203, 68, 137, 225, 212, 131, 63, 255, 8, 226, 0,
+		106, 15, 224, 97, 25, 221, 7, 9, 140, 188, 22, 236, 202, 226,
+		132, 61, 35, 204, 131, 118, 159, 104, 87, 151, 172, 21, 154,
+		230, 228, 215, 186, 158, 55, 205, 239, 167, 195, 162, 253,
+		71, 183, 120, 3, 115, 111, 80, 30, 44, 74, 169, 1, 3, 61,
+		100, 194, 95, 95, 156, 41, 214, 120, 200, 48, 247, 60, 207,
+		3, 120, 161, 76, 248, 119, 59, 228, 175, 253, 103, 81, 10,
+		154, 211, 127, 145, 195, 236, 166, 96, 56, 235, 163, 41, 105,
+		130, 146, 160, 159, 81, 142, 169, 88, 109, 9, 205, 219, 165,
+		143, 176, 20, 210, 42, 214, 212, 111, 231, 113, 187, 222,
+		132, 183, 55, 203, 168, 150, 153, 15, 217, 90, 50, 109, 116,
+		178, 220, 185, 47, 97, 180, 9, 226, 19, 51, 232, 111, 99,
+		130, 119, 82, 97, 167, 221, 74, 147, 217, 165, 125, 246, 74,
+		187, 125, 49, 71, 217, 131, 216, 30, 218, 228, 70, 9, 39,
+		38, 46, 253, 229, 254, 15, 44, 185, 83, 142, 147, 226, 187,
+		6, 23, 178, 22, 190, 41, 50, 180, 221, 59, 108, 216, 3, 133,
+		46, 243, 98, 199, 132, 174, 143, 119, 93, 218, 27, 63, 114,
+		186, 191, 126, 169, 241, 204, 222, 9, 48, 116, 2, 88, 184,
+		88, 243, 131, 209, 198, 254, 1, 11, 23, 235, 163, 253, 92,
+		149, 148, 254, 250, 252, 226, 227, 249, 27, 173, 74, 150,
+		240, 6, 161, 172, 100, 144, 67, 230, 223, 125, 58, 191, 186,
+		188, 186, 252, 120, 246, 191, 115, 221, 208, 28, 223, 87,
+		115, 219, 58, 191, 87, 201, 107, 101, 234, 188, 18, 7, 254,
+		238, 187, 96, 184, 79, 222, 131, 49, 45, 147, 172, 120, 38,
+		72, 198, 178, 62, 99, 37, 98, 194, 190, 77, 19, 143, 36, 110,
+		42, 253, 45, 23, 121, 167, 90, 76, 191, 79, 195, 255, 144,
+		135, 49, 146, 172, 9, 53, 30, 233, 212, 152, 178, 36, 121,
+		252, 232, 210, 31, 62, 182, 202, 169, 220, 106, 63, 178, 197,
+		13, 60, 101, 31, 129, 137, 24, 230, 238, 13, 118, 40, 121,
+		177, 247, 15, 164, 186, 70, 78, 247, 152, 125, 225, 69, 174,
+		141, 76, 25, 90, 85, 91, 107, 150, 146, 251, 235, 243, 203,
+		171, 137, 217, 235, 246, 1, 211, 235, 234, 211, 165, 230,
+		117, 31, 71, 239, 58, 156, 93, 76, 13, 109, 213, 33, 244,
+		225, 61, 217, 27, 126, 209, 154, 67, 240, 143, 64, 206, 200,
+		54, 204, 224, 195, 7, 56, 206, 91, 21, 121, 94, 176, 234,
+		43, 102, 148, 244, 23, 52, 9, 226, 135, 112, 21, 140, 161,
+		182, 237, 27, 67, 10, 224, 49, 243, 43, 228, 123, 154, 226,
+		154, 112, 146, 235, 36, 246, 188, 35, 207, 8, 51, 100, 51,
+		71, 7, 60, 123, 170, 100, 156, 65, 28, 221, 211, 69, 49, 157,
+		17, 175, 123, 216, 70, 195, 211, 26, 183, 134, 163, 101, 27,
+		253, 154, 159, 80, 103, 93, 195, 60, 146, 85, 175, 94, 8,
+		101, 208, 52, 51, 107, 132, 234, 201, 104, 175, 108, 139,
+		85, 165, 47, 152, 19, 63, 27, 31, 188, 234, 18, 104, 111,
+		96, 229, 211, 213, 228, 86, 233, 238, 201, 100, 88, 251, 101,
+		109, 207, 129, 110, 88, 234, 193, 117, 131, 190, 198, 84,
+		179, 112, 175, 136, 17, 202, 177, 202, 120, 186, 174, 27,
+		175, 249, 111, 0, 80, 75, 7, 8, 216, 214, 235, 157, 82, 3,
+		0, 0, 163, 12, 0, 0, 80, 75, 3, 4, 20, 0, 8, 0, 8, 0, 0, 13,
+		110, 77, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 31, 0, 9, 0,
+		115, 112, 97, 114, 113, 108, 47, 101, 118, 101, 110, 116,
+		45, 112, 97, 114, 116, 105, 99, 105, 112, 97, 110, 116, 46,
+		115, 112, 97, 114, 113, 108, 85, 84, 5, 0, 1, 241, 124, 235,
+		91, 228, 145, 95, 75, 243, 48, 20, 135, 239, 243, 41, 194,
+		222, 251, 230, 29, 67, 29, 69, 182, 139, 25, 177, 48, 112,
+		110, 162, 222, 230, 207, 89, 141, 118, 73, 72, 207, 86, 198,
+		232, 119, 151, 166, 69, 91, 145, 221, 121, 101, 175, 154,
+		195, 115, 158, 147, 147, 223, 106, 205, 111, 179, 23, 42,
+		117, 74, 175, 95, 17, 125, 202, 88, 85, 85, 137, 52, 185,
+		22, 40, 18, 229, 118, 44, 232, 237, 191, 25, 233, 192, 202,
+		188, 27, 41, 74, 232, 225, 177, 146, 148, 192, 156, 69, 87,
+		184, 252, 216, 163, 53, 14, 189, 13, 28, 197, 46, 228, 204,
+		7, 231, 153, 54, 1, 20, 178, 94, 203, 153, 14, 176, 104, 240,
+		200, 102, 100, 195, 151, 124, 241, 72, 231, 94, 4, 52, 202,
+		120, 97, 113, 112, 88, 10, 9, 5, 157, 227, 209, 67, 251, 251,
+		124, 199, 215, 156, 158, 8, 165, 148, 86, 58, 61, 157, 18,
+		126, 0, 139, 217, 77, 93, 199, 91, 174, 174, 198, 255, 135,
+		186, 36, 178, 131, 82, 4, 39, 227, 214, 219, 1, 27, 190, 126,
+		202, 22, 252, 235, 97, 138, 56, 175, 157, 212, 124, 82, 167,
+		37, 132, 131, 81, 176, 18, 65, 236, 250, 160, 205, 247, 34,
+		7, 58, 2, 59, 234, 108, 53, 169, 9, 105, 182, 47, 127, 90,
+		191, 105, 101, 15, 227, 233, 244, 98, 114, 73, 200, 31, 141,
+		174, 219, 255, 124, 106, 78, 190, 129, 106, 188, 116, 126,
+		16, 197, 30, 190, 213, 63, 131, 108, 185, 204, 150, 40, 172,
+		130, 251, 237, 111, 133, 250, 49, 0, 80, 75, 7, 8, 185, 203,
+		79, 46, 25, 1, 0, 0, 103, 3, 0, 0, 80, 75, 3, 4, 20, 0, 8,
+		0, 8, 0, 4, 95, 83, 93, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		19, 0, 9, 0, 115, 112, 97, 114, 113, 108, 47, 116, 101, 114,
+		109, 115, 46, 115, 112, 97, 114, 113, 108, 85, 84, 5, 0, 1,
+		89, 5, 214, 106, 116, 82, 77, 107, 131, 64, 16, 189, 251,
+		43, 30, 33, 7, 133, 224, 15, 144, 182, 146, 38, 182, 21, 196,
+		66, 52, 205, 121, 163, 155, 116, 27, 191, 112, 183, 148, 176,
+		204, 127, 47, 174, 198, 120, 136, 183, 157, 153, 55, 239,
+		189, 217, 153, 36, 136, 130, 77, 138, 109, 152, 164, 97, 188,
+		73, 45, 0, 240, 21, 111, 203, 160, 82, 66, 93, 239, 113, 122,
+		109, 248, 61, 178, 112, 248, 8, 118, 1, 180, 5, 124, 173,
+		163, 125, 144, 192, 246, 235, 227, 15, 207, 148, 99, 146,
+		128, 214, 104, 89, 117, 230, 112, 13, 149, 224, 18, 68, 90,
+		67, 156, 224, 130, 200, 126, 210, 218, 37, 122, 113, 180,
+		6, 175, 242, 190, 198, 171, 156, 200, 2, 8, 174, 133, 129,
+		231, 53, 140, 183, 55, 110, 172, 147, 169, 59, 199, 192, 8,
+		251, 56, 252, 140, 7, 248, 13, 233, 55, 83, 164, 1, 2, 126,
+		211, 214, 13, 111, 213, 21, 127, 226, 34, 142, 76, 114, 47,
+		23, 45, 207, 212, 166, 96, 162, 236, 122, 102, 113, 183, 206,
+		238, 31, 238, 221, 135, 225, 17, 42, 94, 154, 94, 26, 125,
+		79, 213, 229, 165, 150, 30, 43, 84, 196, 142, 188, 232, 43,
+		131, 146, 153, 110, 193, 10, 193, 228, 98, 156, 174, 211,
+		120, 56, 219, 148, 50, 251, 230, 37, 243, 114, 46, 179, 86,
+		52, 74, 212, 213, 3, 222, 73, 245, 33, 251, 176, 14, 99, 171,
+		91, 207, 172, 90, 155, 159, 164, 87, 204, 184, 55, 249, 57,
+		254, 126, 183, 22, 240, 22, 70, 105, 176, 179, 163, 117, 252,
+		110, 27, 167, 14, 194, 24, 246, 120, 38, 75, 177, 194, 178,
+		128, 247, 12, 55, 98, 213, 249, 151, 157, 39, 23, 179, 20,
+		32, 90, 97, 188, 149, 133, 214, 29, 184, 127, 244, 41, 199,
+		129, 107, 209, 255, 0, 80, 75, 7, 8, 189, 30, 234, 56, 70,
+		1, 0, 0, 208, 2, 0, 0, 80, 75, 3, 4, 20, 0, 8, 0, 8, 0, 0,
+		13, 110, 77, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 18, 0, 9,
+		0, 115, 112, 97, 114, 113, 108, 47, 116, 101, 115, 116, 46,
+		115, 112, 97, 114, 113, 108, 85, 84, 5, 0, 1, 241, 124, 235,
+		91, 124, 143, 81, 75, 195, 48, 20, 133, 223, 251, 43, 46,
+		235, 123, 99, 145, 234, 40, 178, 61, 212, 136, 129, 41, 181,
+		45, 234, 107, 218, 92, 99, 88, 151, 148, 244, 186, 50, 100,
+		255, 93, 86, 203, 44, 10, 230, 49, 231, 59, 31, 231, 230,
+		5, 191, 19, 175, 48, 168, 20, 110, 222, 137, 186, 148, 177,
+		97, 24, 162, 193, 108, 141, 146, 36, 35, 231, 53, 67, 75,
+		134, 14, 108, 21, 156, 97, 250, 135, 238, 188, 235, 152, 50,
+		30, 27, 154, 85, 204, 214, 212, 178, 199, 89, 111, 252, 137,
+		122, 100, 206, 146, 107, 157, 62, 132, 103, 186, 254, 181,
+		166, 54, 122, 212, 55, 110, 199, 188, 122, 11, 87, 65, 8,
+		132, 61, 25, 171, 131, 146, 111, 120, 86, 193, 173, 40, 43,
+		241, 152, 85, 176, 86, 146, 16, 94, 238, 121, 193, 225, 51,
+		0, 0, 88, 227, 30, 45, 141, 171, 243, 203, 248, 116, 234,
+		83, 124, 189, 76, 174, 98, 136, 254, 228, 201, 50, 153, 12,
+		223, 89, 201, 139, 103, 145, 241, 159, 253, 173, 172, 177,
+		157, 196, 167, 87, 171, 180, 71, 191, 55, 13, 230, 210, 203,
+		221, 28, 180, 250, 67, 106, 132, 5, 218, 197, 100, 59, 6,
+		71, 216, 136, 7, 81, 65, 124, 241, 53, 0, 80, 75, 7, 8, 204,
+		170, 159, 25, 228, 0, 0, 0, 121, 1, 0, 0, 80, 75, 1, 2, 20,
+		3, 20, 0, 8, 0, 8, 0, 0, 13, 110, 77, 113, 118, 5, 211, 78,
+		1, 0, 0, 11, 2, 0, 0, 28, 0, 9, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		180, 129, 0, 0, 0, 0, 115, 112, 97, 114, 113, 108, 47, 100,
+		97, 116, 101, 100, 45, 101, 110, 116, 105, 116, 105, 101,
+		115, 46, 115, 112, 97, 114, 113, 108, 85, 84, 5, 0, 1, 241,
+		124, 235, 91, 80, 75, 1, 2, 20, 3, 20, 0, 8, 0, 8, 0, 60,
+		109, 83, 93, 216, 214, 235, 157, 82, 3, 0, 0, 163, 12, 0,
+		0, 20, 0, 9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 164, 129, 161, 1,
 		0, 0, 115, 112, 97, 114, 113, 108, 47, 101, 110, 116, 105,
 		116, 121, 46, 115, 112, 97, 114, 113, 108, 85, 84, 5, 0, 1,
-		63, 203, 142, 91, 80, 75, 1, 2, 20, 3, 20, 0, 8, 0, 8, 0,
-		53, 118, 35, 77, 135, 192, 250, 145, 227, 0, 0, 0, 146, 1,
-		0, 0, 31, 0, 9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 164, 129, 1, 3,
-		0, 0, 115, 112, 97, 114, 113, 108, 47, 101, 118, 101, 110,
-		116, 45, 112, 97, 114, 116, 105, 99, 105, 112, 97, 110, 116,
-		46, 115, 112, 97, 114, 113, 108, 85, 84, 5, 0, 1, 7, 74, 141,
-		91, 80, 75, 1, 2, 20, 3, 20, 0, 8, 0, 8, 0, 151, 169, 33,
-		77, 204, 170, 159, 25, 231, 0, 0, 0, 121, 1, 0, 0, 18, 0,
-		9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 164, 129, 58, 4, 0, 0, 115,
-		112, 97, 114, 113, 108, 47, 116, 101, 115, 116, 46, 115, 112,
-		97, 114, 113, 108, 85, 84, 5, 0, 1, 207, 0, 139, 91, 80, 75,
-		5, 6, 0, 0, 0, 0, 4, 0, 4, 0, 61, 1, 0, 0, 106, 5, 0, 0, 0,
-		0,
+		36, 30, 214, 106, 80, 75, 1, 2, 20, 3, 20, 0, 8, 0, 8, 0,
+		0, 13, 110, 77, 185, 203, 79, 46, 25, 1, 0, 0, 103, 3, 0,
+		0, 31, 0, 9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 180, 129, 62, 5, 0,
+		0, 115, 112, 97, 114, 113, 108, 47, 101, 118, 101, 110, 116,
+		45, 112, 97, 114, 116, 105, 99, 105, 112, 97, 110, 116, 46,
+		115, 112, 97, 114, 113, 108, 85, 84, 5, 0, 1, 241, 124, 235,
+		91, 80, 75, 1, 2, 20, 3, 20, 0, 8, 0, 8, 0, 4, 95, 83, 93,
+		189, 30, 234, 56, 70, 1, 0, 0, 208, 2, 0, 0, 19, 0, 9, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 164, 129, 173, 6, 0, 0, 115, 112,
+		97, 114, 113, 108, 47, 116, 101, 114, 109, 115, 46, 115, 112,
+		97, 114, 113, 108, 85, 84, 5, 0, 1, 89, 5, 214, 106, 80, 75,
+		1, 2, 20, 3, 20, 0, 8, 0, 8, 0, 0, 13, 110, 77, 204, 170,
+		159, 25, 228, 0, 0, 0, 121, 1, 0, 0, 18, 0, 9, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 180, 129, 61, 8, 0, 0, 115, 112, 97, 114, 113,
+		108, 47, 116, 101, 115, 116, 46, 115, 112, 97, 114, 113, 108,
+		85, 84, 5, 0, 1, 241, 124, 235, 91, 80, 75, 5, 6, 0, 0, 0,
+		0, 5, 0, 5, 0, 135, 1, 0, 0, 106, 9, 0, 0, 0, 0,
 	})
 }
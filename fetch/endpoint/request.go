@@ -7,6 +7,8 @@ package endpoint
 
 import (
 	"math"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -18,12 +20,49 @@ type entityURI string
 
 type BindingCallbackFunc func(*Binding) error
 
-func RequestWikidataEvents(startYear, endYear int, callback BindingCallbackFunc) error {
+// Options select the optional statement data requested alongside each entity.
+type Options struct {
+	// References adds the stated in (P248), reference URL (P854) and retrieved (P813)
+	// values of each statement's references to the entity bindings.
+	References bool
+	// Languages is the fallback chain for entity labels and descriptions. When set, the label
+	// in each language is also requested as a separate term binding.
+	Languages []string
+}
+
+var languageCode = regexp.MustCompile("^[a-z]{2,3}(-[a-z0-9]+)*$")
+
+func (Ω Options) validate() error {
+	for _, l := range Ω.Languages {
+		if !languageCode.MatchString(l) {
+			return errors.Errorf("invalid language code [%s]", l)
+		}
+	}
+	return nil
+}
+
+func (Ω Options) languages() []string {
+	if len(Ω.Languages) == 0 {
+		return []string{"en"}
+	}
+	return Ω.Languages
+}
+
+// languageChain is the comma separated fallback list understood by the wikibase:label service.
+func (Ω Options) languageChain() string {
+	return strings.Join(Ω.languages(), ",")
+}
+
+func RequestWikidataEvents(startYear, endYear int, opts Options, callback BindingCallbackFunc) error {
 
 	if startYear == 0 || endYear == 0 {
 		return errors.New("start and end year required")
 	}
 
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
 	entityBatches, err := fetchWikidataEntities(startYear, endYear)
 	if err != nil {
 		return err
@@ -52,7 +91,7 @@ func RequestWikidataEvents(startYear, endYear int, callback BindingCallbackFunc)
 				defer func() {
 					lmtr <- struct{}{}
 				}()
-				if err := fetchEntityBatch(eb, callback); err != nil {
+				if err := fetchEntityBatch(eb, opts, callback); err != nil {
 					return err
 				}
 				completed++
@@ -94,7 +133,7 @@ type queryResponse struct {
 // "http://dbpedia.org/sparql"
 // TODO: For smaller queries this is fine, but ensure this isn't paginated.
 func fetchWikidataEntities(yearStart int, yearEnd int) ([][entityBatchSize]entityURI, error) {
-	s, err := parseTemplate("sparql/dated-entities.sparql", &struct {
+	s, err := parseTemplate(datedEntitiesTemplate, &struct {
 		YearEnd   int
 		YearStart int
 	}{yearEnd, yearStart})
@@ -103,7 +142,7 @@ func fetchWikidataEntities(yearStart int, yearEnd int) ([][entityBatchSize]entit
 	}
 
 	q := &query{
-		Endpoint: "https://query.wikidata.org/sparql",
+		Endpoint: WikidataEndpoint,
 		Body:     s,
 	}
 	requestResponse, err := q.request()
@@ -128,12 +167,19 @@ func fetchWikidataEntities(yearStart int, yearEnd int) ([][entityBatchSize]entit
 		return nil, nil
 	}
 
+	// Sorted so that each run requests the same batches.
+	sorted := make([]entityURI, 0, len(entities))
+	for e := range entities {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
 	batchCount := int(math.Ceil(float64(len(entities)) / entityBatchSize))
 
 	batchArray := make([][entityBatchSize]entityURI, batchCount)
 	batch := 0
 	index := 0
-	for e := range entities {
+	for _, e := range sorted {
 		batchArray[batch][index] = e
 		index++
 		if index >= entityBatchSize {
@@ -145,16 +191,39 @@ func fetchWikidataEntities(yearStart int, yearEnd int) ([][entityBatchSize]entit
 	return batchArray, nil
 }
 
-func fetchEntityBatch(entities [entityBatchSize]entityURI, callback BindingCallbackFunc) error {
+func fetchEntityBatch(entities [entityBatchSize]entityURI, opts Options, callback BindingCallbackFunc) error {
+
+	templateStruct := &struct {
+		Entities      [entityBatchSize]entityURI
+		References    bool
+		LanguageChain string
+		Languages     []string
+		Labels        bool
+	}{
+		Entities:      entities,
+		References:    opts.References,
+		LanguageChain: opts.languageChain(),
+		Languages:     opts.languages(),
+		// The entity query already holds the label of the only language.
+		Labels: len(opts.Languages) > 0,
+	}
+
+	if err := requestBindings(entityTemplate, templateStruct, callback); err != nil {
+		return err
+	}
+
+	// Aliases and descriptions in each language.
+	return requestBindings(termsTemplate, templateStruct, callback)
+}
+
+func requestBindings(queryFile string, templateStruct interface{}, callback BindingCallbackFunc) error {
 
-	s, err := parseTemplate("sparql/entity.sparql", &struct {
-		Entities [entityBatchSize]entityURI
-	}{Entities: entities})
+	s, err := parseTemplate(queryFile, templateStruct)
 	if err != nil {
 		return err
 	}
 	q := &query{
-		Endpoint: "https://query.wikidata.org/sparql",
+		Endpoint: WikidataEndpoint,
 		Body:     s,
 	}
 	requestResponse, err := q.request()
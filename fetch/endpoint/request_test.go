@@ -24,7 +24,7 @@ func TestBinding(t *testing.T) {
 	}
 	lat, lng, err := b.MustCoordinates("coordinate")
 	if err != nil {
-		t.Errorf(err.Error())
+		t.Error(err)
 	}
 	if lat != 41.1 {
 		t.Errorf("Latitude should be %f, not %+v", 41.1, lat)
@@ -61,7 +61,27 @@ func TestEventRequest(t *testing.T) {
 		total++
 		return nil
 	}
-	assert.NoError(t, RequestWikidataEvents(-2, 2, callback))
+	assert.NoError(t, RequestWikidataEvents(-2, 2, Options{}, callback))
 
 	assert.Equal(t, 9397, total)
 }
+
+func TestTermsTemplate(t *testing.T) {
+	opts := Options{Languages: []string{"zh", "en"}}
+	assert.NoError(t, opts.validate())
+	assert.Equal(t, "zh,en", opts.languageChain())
+	assert.Error(t, Options{Languages: []string{`en" . } #`}}.validate())
+
+	assert.Equal(t, "en", Options{}.languageChain())
+
+	s, err := parseTemplate("sparql/terms.sparql", &struct {
+		Entities  [entityBatchSize]entityURI
+		Languages []string
+		Labels    bool
+	}{Entities: [entityBatchSize]entityURI{"http://www.wikidata.org/entity/Q7183"}, Languages: opts.Languages, Labels: true})
+	assert.NoError(t, err)
+	assert.Contains(t, s, `skos:altLabel ?term`)
+	assert.Contains(t, s, `rdfs:label ?term`)
+	assert.Contains(t, s, `(<http://www.wikidata.org/entity/Q7183>)`)
+	assert.Contains(t, s, `FILTER(LANG(?term) IN ("zh", "en"))`)
+}
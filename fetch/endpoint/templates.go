@@ -7,6 +7,8 @@ package endpoint
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"io/ioutil"
 	"text/template"
 
@@ -16,16 +18,52 @@ import (
 
 //go:generate parcello -r -i *.go -i .DS_Store -i dbpedia* -i *test_*
 
-func parseTemplate(queryFile string, templateStruct interface{}) (string, error) {
+// WikidataEndpoint is the SPARQL endpoint every query is sent to.
+const WikidataEndpoint = "https://query.wikidata.org/sparql"
+
+// The templates of the queries for events in a range of years and for the statements and terms of
+// each batch of them.
+const (
+	datedEntitiesTemplate = "sparql/dated-entities.sparql"
+	entityTemplate        = "sparql/entity.sparql"
+	termsTemplate         = "sparql/terms.sparql"
+)
 
+// Template identifies a query template by the SHA-256 of its contents.
+type Template struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// Templates returns the query templates that events are requested with.
+func Templates() ([]Template, error) {
+	res := []Template{}
+	for _, name := range []string{datedEntitiesTemplate, entityTemplate, termsTemplate} {
+		b, err := readTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		res = append(res, Template{Name: name, SHA256: hex.EncodeToString(sum[:])})
+	}
+	return res, nil
+}
+
+func readTemplate(queryFile string) ([]byte, error) {
 	file, err := parcello.Open(queryFile)
 	if err != nil {
-		return "", errors.Wrapf(err, "could not find sparql file %s", queryFile)
+		return nil, errors.Wrapf(err, "could not find sparql file %s", queryFile)
 	}
-
+	defer file.Close()
 	b, err := ioutil.ReadAll(file)
+	return b, errors.Wrapf(err, "could not read sparql file %s", queryFile)
+}
+
+func parseTemplate(queryFile string, templateStruct interface{}) (string, error) {
+
+	b, err := readTemplate(queryFile)
 	if err != nil {
-		return "", errors.Wrapf(err, "could not read sparql file %s", queryFile)
+		return "", err
 	}
 
 	query := bytes.NewBuffer([]byte{})
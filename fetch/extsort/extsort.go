@@ -0,0 +1,285 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+// Package extsort sorts more lines than fit in memory, by spilling sorted chunks to temporary
+// files and merging them.
+package extsort
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultChunkSize is the number of lines held in memory before they are spilled to a file.
+const DefaultChunkSize = 100000
+
+// maxLineSize bounds the length of a line read back from a chunk file.
+const maxLineSize = 64 * 1024 * 1024
+
+// DefaultFanIn is the most chunk files merged at once. More chunks are first merged into
+// intermediate files, so that a large sort does not run out of file descriptors.
+const DefaultFanIn = 64
+
+// Sorter collects lines and returns them in byte order. Lines are compared whole, so callers order
+// records by prefixing them with a sortable key, and equal keys are ordered by the rest of the
+// line. It is safe for concurrent use.
+type Sorter struct {
+	sync.Mutex
+	chunkSize int
+	fanIn     int
+	lines     []string
+	dir       string
+	chunks    []string
+	// files counts the chunk files created, to name the next.
+	files int
+}
+
+func New(chunkSize int) *Sorter {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &Sorter{chunkSize: chunkSize, fanIn: DefaultFanIn}
+}
+
+// Add adds a line, which must not contain a newline.
+func (Ω *Sorter) Add(line string) error {
+	if strings.ContainsRune(line, '\n') {
+		return errors.Errorf("line contains a newline [%s]", line)
+	}
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.lines = append(Ω.lines, line)
+	if len(Ω.lines) < Ω.chunkSize {
+		return nil
+	}
+	return Ω.spill()
+}
+
+// spill writes the sorted lines in memory to a new chunk file. The caller must hold the lock.
+func (Ω *Sorter) spill() error {
+	sort.Strings(Ω.lines)
+	err := Ω.chunk(func(w *bufio.Writer) error {
+		for _, line := range Ω.lines {
+			if _, err := w.WriteString(line + "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	Ω.lines = Ω.lines[:0]
+	return err
+}
+
+// chunk writes a new chunk file with the lines written by fn, which must be sorted. The caller
+// must hold the lock.
+func (Ω *Sorter) chunk(fn func(w *bufio.Writer) error) error {
+	if Ω.dir == "" {
+		dir, err := ioutil.TempDir("", "wikivents-sort")
+		if err != nil {
+			return errors.Wrap(err, "could not create sort directory")
+		}
+		Ω.dir = dir
+	}
+	Ω.files++
+	name := filepath.Join(Ω.dir, fmt.Sprintf("%06d", Ω.files))
+	f, err := os.Create(name)
+	if err != nil {
+		return errors.Wrap(err, "could not create sort chunk")
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := fn(w); err != nil {
+		return errors.Wrapf(err, "could not write sort chunk %s", name)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrapf(err, "could not write sort chunk %s", name)
+	}
+	Ω.chunks = append(Ω.chunks, name)
+	return errors.Wrapf(f.Close(), "could not close sort chunk %s", name)
+}
+
+// source is a sorted run of lines, from a chunk file or from memory.
+type source struct {
+	line    string
+	file    *os.File
+	scanner *bufio.Scanner
+	lines   []string
+}
+
+// open opens the chunk file as a source.
+func open(name string) (*source, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open sort chunk %s", name)
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &source{file: f, scanner: scanner}, nil
+}
+
+// next advances to the following line, returning false when the source is exhausted, at which
+// point its file is closed.
+func (Ω *source) next() (bool, error) {
+	if Ω.scanner == nil {
+		if len(Ω.lines) == 0 {
+			return false, nil
+		}
+		Ω.line, Ω.lines = Ω.lines[0], Ω.lines[1:]
+		return true, nil
+	}
+	if Ω.scanner.Scan() {
+		Ω.line = Ω.scanner.Text()
+		return true, nil
+	}
+	if err := Ω.scanner.Err(); err != nil {
+		return false, errors.Wrap(err, "could not read sort chunk")
+	}
+	return false, Ω.close()
+}
+
+// close closes the file of the source, if it is still open.
+func (Ω *source) close() error {
+	if Ω.file == nil {
+		return nil
+	}
+	err := Ω.file.Close()
+	Ω.file = nil
+	return errors.Wrap(err, "could not close sort chunk")
+}
+
+type sources []*source
+
+func (Ω sources) Len() int            { return len(Ω) }
+func (Ω sources) Less(i, j int) bool  { return Ω[i].line < Ω[j].line }
+func (Ω sources) Swap(i, j int)       { Ω[i], Ω[j] = Ω[j], Ω[i] }
+func (Ω *sources) Push(x interface{}) { *Ω = append(*Ω, x.(*source)) }
+func (Ω *sources) Pop() interface{} {
+	old := *Ω
+	s := old[len(old)-1]
+	*Ω = old[:len(old)-1]
+	return s
+}
+
+// merge calls fn with every line of the sources in order, and closes them.
+func merge(all []*source, fn func(line string) error) error {
+	defer func() {
+		for _, s := range all {
+			s.close()
+		}
+	}()
+	h := sources{}
+	for _, s := range all {
+		ok, err := s.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, s)
+		}
+	}
+	heap.Init(&h)
+	for h.Len() > 0 {
+		s := h[0]
+		if err := fn(s.line); err != nil {
+			return err
+		}
+		ok, err := s.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
+
+// openChunks opens the chunk files as sources, closing them again if any fails to open.
+func openChunks(names []string) ([]*source, error) {
+	res := []*source{}
+	for _, name := range names {
+		s, err := open(name)
+		if err != nil {
+			for _, opened := range res {
+				opened.close()
+			}
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// compact merges the oldest chunk files into one until at most the fan in remain. The caller must
+// hold the lock.
+func (Ω *Sorter) compact() error {
+	for len(Ω.chunks) > Ω.fanIn {
+		names := Ω.chunks[:Ω.fanIn]
+		all, err := openChunks(names)
+		if err != nil {
+			return err
+		}
+		Ω.chunks = Ω.chunks[Ω.fanIn:]
+		if err := Ω.chunk(func(w *bufio.Writer) error {
+			return merge(all, func(line string) error {
+				_, err := w.WriteString(line + "\n")
+				return err
+			})
+		}); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := os.Remove(name); err != nil {
+				return errors.Wrapf(err, "could not remove sort chunk %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Sort calls fn with every line in order, merging the chunk files with the lines still in memory,
+// and then removes the chunk files. The sorter is empty afterwards.
+func (Ω *Sorter) Sort(fn func(line string) error) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	defer Ω.reset()
+
+	if err := Ω.compact(); err != nil {
+		return err
+	}
+	sort.Strings(Ω.lines)
+	all, err := openChunks(Ω.chunks)
+	if err != nil {
+		return err
+	}
+	return merge(append(all, &source{lines: Ω.lines}), fn)
+}
+
+// Abort removes the chunk files and empties the sorter without sorting, for a run that fails
+// before Sort. It does nothing once Sort has run.
+func (Ω *Sorter) Abort() {
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.reset()
+}
+
+// reset removes the chunk files and empties the sorter. The caller must hold the lock.
+func (Ω *Sorter) reset() {
+	if Ω.dir != "" {
+		os.RemoveAll(Ω.dir)
+	}
+	Ω.lines, Ω.dir, Ω.chunks, Ω.files = nil, "", nil, 0
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package extsort
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSorter(t *testing.T) {
+	s := New(3)
+	expected := []string{}
+	for _, i := range []int{7, 3, 9, 1, 1, 12, 5, 0, 8, 4} {
+		line := fmt.Sprintf("%02d", i)
+		expected = append(expected, line)
+		assert.NoError(t, s.Add(line))
+	}
+	sort.Strings(expected)
+	assert.Len(t, s.chunks, 3)
+	dir := s.dir
+
+	lines := []string{}
+	assert.NoError(t, s.Sort(func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}))
+	assert.Equal(t, expected, lines)
+
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+	assert.Error(t, s.Add("a\nb"))
+
+	// With more chunks than the fan in, the chunks are merged in passes.
+	s = New(1)
+	s.fanIn = 2
+	for _, line := range expected {
+		assert.NoError(t, s.Add(line))
+	}
+	lines = []string{}
+	assert.NoError(t, s.Sort(func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}))
+	assert.Equal(t, expected, lines)
+}
+
+func TestAbort(t *testing.T) {
+	s := New(1)
+	assert.NoError(t, s.Add("a"))
+	assert.NoError(t, s.Add("b"))
+	dir := s.dir
+	_, err := os.Stat(dir)
+	assert.NoError(t, err)
+
+	s.Abort()
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	lines := []string{}
+	assert.NoError(t, s.Sort(func(line string) error {
+		lines = append(lines, line)
+		return nil
+	}))
+	assert.Empty(t, lines)
+}
@@ -13,10 +13,41 @@ import (
 	"github.com/pkg/errors"
 )
 
-func WikidataEvents(startYear, endYear int, rdfWriter, schemaWriter io.Writer) error {
+// Options configure the optional data fetched for each entity and how it is written.
+type Options struct {
+	References parse.ReferenceMode
+	// Languages is the fallback chain for labels, each of which is also written as a language tagged label.
+	Languages []string
+	// Conflicts resolves predicates that receive values of more than one schema type.
+	Conflicts parse.ConflictMode
+	// Predicates names property predicates by label or by property ID.
+	Predicates parse.PredicateMode
+	// Dictionary receives the predicate dictionary as JSON, if set.
+	Dictionary io.Writer
+	// Dates adds the full date and precision of each time value as facets.
+	Dates bool
+}
+
+// WikidataEvents requests the events between the years and writes them to the sink, returning the
+// counts of what was received and written.
+func WikidataEvents(startYear, endYear int, opts Options, sink parse.Sink) (parse.Stats, error) {
 	if (startYear == 0 && endYear == 0) || (endYear-startYear < 0) {
-		return errors.New("valid start and end year required")
+		return parse.Stats{}, errors.New("valid start and end year required")
+	}
+	writer := parse.NewWriter(sink, parse.Options{
+		References: opts.References,
+		Conflicts:  opts.Conflicts,
+		Predicates: opts.Predicates,
+		Dictionary: opts.Dictionary,
+		Dates:      opts.Dates,
+	})
+	if err := endpoint.RequestWikidataEvents(startYear, endYear, endpoint.Options{
+		References: opts.References != parse.ReferenceModeNone,
+		Languages:  opts.Languages,
+	}, writer.ParseBinding); err != nil {
+		writer.Abort()
+		return writer.Stats(), err
 	}
-	writer := parse.NewWriter(rdfWriter, schemaWriter)
-	return endpoint.RequestWikidataEvents(startYear, endYear, writer.ParseBinding)
+	err := writer.Close()
+	return writer.Stats(), err
 }
@@ -0,0 +1,110 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package parse
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// PredicateDefinition describes the Wikidata property a predicate was written from.
+type PredicateDefinition struct {
+	Predicate Predicate  `json:"predicate"`
+	Property  string     `json:"property"`
+	Label     string     `json:"label"`
+	Datatype  string     `json:"datatype"`
+	Type      SchemaType `json:"type"`
+	// Collisions are the other properties written to the predicate, because their labels are the same.
+	Collisions []string `json:"collisions,omitempty"`
+}
+
+// predicateDictionary maps each property predicate to its property, after schema conflicts are resolved.
+type predicateDictionary struct {
+	sync.Mutex
+	properties map[string]property
+	predicates map[Predicate]PredicateDefinition
+}
+
+func newPredicateDictionary() *predicateDictionary {
+	return &predicateDictionary{
+		properties: map[string]property{},
+		predicates: map[Predicate]PredicateDefinition{},
+	}
+}
+
+func (Ω *predicateDictionary) property(p property) {
+	if p.ID == "" {
+		return
+	}
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.properties[p.ID] = p
+}
+
+func (Ω *predicateDictionary) predicate(p Predicate, propertyID string, t SchemaType) {
+	if propertyID == "" {
+		return
+	}
+	Ω.Lock()
+	defer Ω.Unlock()
+	d, ok := Ω.predicates[p]
+	if !ok {
+		Ω.predicates[p] = PredicateDefinition{Predicate: p, Property: propertyID, Type: t}
+		return
+	}
+	d.Type = t
+	if d.Property == propertyID || containsString(d.Collisions, propertyID) {
+		Ω.predicates[p] = d
+		return
+	}
+	logrus.Warnf("Predicate [%s] is written from properties [%s] and [%s], which share a label; use --predicates id to keep them apart", p, d.Property, propertyID)
+	// The lowest property ID defines the predicate, so the dictionary does not depend on the order values arrive in.
+	ids := append([]string{d.Property, propertyID}, d.Collisions...)
+	sort.Strings(ids)
+	d.Property, d.Collisions = ids[0], ids[1:]
+	Ω.predicates[p] = d
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Definitions returns the definition of each property predicate, sorted by predicate.
+func (Ω *predicateDictionary) Definitions() []PredicateDefinition {
+	Ω.Lock()
+	defer Ω.Unlock()
+	res := make([]PredicateDefinition, 0, len(Ω.predicates))
+	for _, d := range Ω.predicates {
+		d.Label = Ω.properties[d.Property].Label
+		d.Datatype = Ω.properties[d.Property].Datatype
+		res = append(res, d)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Predicate < res[j].Predicate
+	})
+	return res
+}
+
+func (Ω *predicateDictionary) Write(w io.Writer) error {
+	b, err := json.MarshalIndent(Ω.Definitions(), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not marshal predicate dictionary")
+	}
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		return errors.Wrap(err, "could not write predicate dictionary")
+	}
+	return nil
+}
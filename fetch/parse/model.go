@@ -6,76 +6,451 @@
 package parse
 
 import (
-	"fmt"
-	"io"
 	"regexp"
+	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/pkg/errors"
 )
 
-type entityID string
+// EntityID is the Wikidata Q-ID of an entity, the hash of a statement reference, or the ID of a
+// statement, such as Q48314-8B8A0D47-4B1D-4B8A-9C6E-2D1F2E1A0C55.
+type EntityID string
 
-func newEntityID(s string) (entityID, error) {
+// IRI is the Wikidata concept URI of the entity, reference or statement.
+func (Ω EntityID) IRI() string {
+	if Ω.IsStatement() {
+		return "http://www.wikidata.org/entity/statement/" + string(Ω)
+	}
+	if Ω.IsReference() {
+		return "http://www.wikidata.org/reference/" + string(Ω)
+	}
+	return "http://www.wikidata.org/entity/" + string(Ω)
+}
+
+// IsReference is true for the ID of a reference node, or of the statement node a reference is
+// attached to, rather than an entity.
+func (Ω EntityID) IsReference() bool {
+	return !strings.HasPrefix(string(Ω), "Q") || Ω.IsStatement()
+}
+
+// IsStatement is true for the ID of a statement, which is the ID of its entity followed by a dash.
+func (Ω EntityID) IsStatement() bool {
+	return strings.Contains(string(Ω), "-")
+}
+
+func newEntityID(s string) (EntityID, error) {
 	if len(s) == 0 || !strings.Contains(s, "wikidata.org/entity/Q") {
-		return entityID(""), errors.Errorf("invalid wikidata entity uri: %s", s)
+		return EntityID(""), errors.Errorf("invalid wikidata entity uri: %s", s)
 	}
-	return entityID(s[strings.LastIndex(s, "/")+1:]), nil
+	return EntityID(s[strings.LastIndex(s, "/")+1:]), nil
 }
 
 type entity struct {
-	ID   entityID
-	Name string
-	Type string
+	ID          EntityID
+	Name        string
+	Description string
+	// NameLang and DescriptionLang are the languages of the fallback chain the name and description
+	// were resolved from, which are empty if neither was found.
+	NameLang        string
+	DescriptionLang string
+	Type            Predicate
 }
 
-func (Ω *entity) Write(rdf *rdf, schema *schema) error {
-	if Ω.Type != "" {
-		pred := newPredicate(predicateEntityType, Ω.Type)
-		if err := rdf.WriteFeature(Ω.ID, pred, ""); err != nil {
+// writeFallback writes the value untagged, which is what Dgraph returns when no language is asked
+// for, and also tagged with the language it was resolved from.
+func (Ω *entity) writeFallback(e *emitter, p Predicate, value, lang string) error {
+	for _, l := range []string{"", lang} {
+		if err := e.Feature(Feature{
+			Entity:     Ω.ID,
+			Predicate:  p,
+			EntityType: Ω.Type,
+			Value:      value,
+			Lang:       l,
+			Type:       SchemaTypeString,
+		}, true); err != nil {
 			return err
 		}
-		if err := schema.Write(pred, schemaTypeDefault); err != nil {
-			return err
+		if lang == "" {
+			break
 		}
 	}
+	return nil
+}
+
+func (Ω *entity) Write(e *emitter) error {
+	if err := e.EntityNode(EntityNode{ID: Ω.ID, Type: Ω.Type}); err != nil {
+		return err
+	}
 	if Ω.Name != "" {
-		pred := newPredicate(predicateFeature, "label")
-		if err := rdf.WriteFeature(Ω.ID, pred, escapeFeatureValue(Ω.Name)); err != nil {
+		if err := Ω.writeFallback(e, PredicateLabel, Ω.Name, Ω.NameLang); err != nil {
 			return err
 		}
-		if err := schema.Write(newPredicate(predicateFeature, "label"), schemaTypeString); err != nil {
+	}
+	if Ω.Description != "" {
+		if err := Ω.writeFallback(e, PredicateDescription, Ω.Description, Ω.DescriptionLang); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+type termType string
+
+const (
+	termTypeLabel       = termType("label")
+	termTypeDescription = termType("description")
+	termTypeAlias       = termType("alias")
+)
+
+var termPredicates = map[termType]Predicate{
+	termTypeLabel:       PredicateLabel,
+	termTypeDescription: PredicateDescription,
+	termTypeAlias:       PredicateAlias,
+}
+
+// term is a label, description or alias of an entity in a single language.
+type term struct {
+	Entity EntityID
+	Type   termType
+	Value  string
+	Lang   string
+}
+
+func (Ω *term) Write(e *emitter) error {
+	if Ω.Type == termTypeAlias {
+		// Aliases of all languages share one list, which the Dgraph formats write untagged.
+		return e.Feature(Feature{
+			Entity:    Ω.Entity,
+			Predicate: PredicateAlias,
+			Value:     Ω.Value,
+			Lang:      Ω.Lang,
+			Type:      SchemaTypeStringList,
+		}, false)
+	}
+	return e.Feature(Feature{
+		Entity:    Ω.Entity,
+		Predicate: termPredicates[Ω.Type],
+		Value:     Ω.Value,
+		Lang:      Ω.Lang,
+		Type:      SchemaTypeString,
+	}, true)
+}
+
+// ReferenceMode determines how statement references are written.
+type ReferenceMode string
+
+const (
+	// ReferenceModeNone ignores references.
+	ReferenceModeNone = ReferenceMode("none")
+	// ReferenceModeFacets writes references as facets on the feature or edge they support. Dgraph
+	// keeps one set of facets for each triple, so only the reference with the lowest ID of each value
+	// is written, which does not depend on the order the references arrive in.
+	ReferenceModeFacets = ReferenceMode("facets")
+	// ReferenceModeNodes writes references as separate nodes, connected to a node for the statement
+	// they support, which holds the claim and value of the statement.
+	ReferenceModeNodes = ReferenceMode("nodes")
+)
+
+// PredicateMode determines whether property predicates are named by label or by property ID.
+type PredicateMode string
+
+const (
+	// PredicateModeLabel names predicates by the English property label, such as e_participant.
+	PredicateModeLabel = PredicateMode("label")
+	// PredicateModeID names predicates by the property ID, such as e_P710, which is stable across label changes.
+	PredicateModeID = PredicateMode("id")
+)
+
+func NewPredicateMode(s string) (PredicateMode, error) {
+	switch m := PredicateMode(s); m {
+	case PredicateModeLabel, PredicateModeID:
+		return m, nil
+	case "":
+		return PredicateModeLabel, nil
+	default:
+		return "", errors.Errorf("unknown predicate mode [%s], expected label or id", s)
+	}
+}
+
+// property is the Wikidata property a value was read from.
+type property struct {
+	ID       string
+	Label    string
+	Datatype string
+}
+
+func NewReferenceMode(s string) (ReferenceMode, error) {
+	switch m := ReferenceMode(s); m {
+	case ReferenceModeNone, ReferenceModeFacets, ReferenceModeNodes:
+		return m, nil
+	case "":
+		return ReferenceModeNone, nil
+	default:
+		return "", errors.Errorf("unknown reference mode [%s], expected none, facets or nodes", s)
+	}
+}
+
+func newStatementID(s string) (EntityID, error) {
+	if len(s) == 0 || !strings.Contains(s, "wikidata.org/entity/statement/") {
+		return EntityID(""), errors.Errorf("invalid wikidata statement uri: %s", s)
+	}
+	return EntityID(s[strings.LastIndex(s, "/")+1:]), nil
+}
+
+func newReferenceID(s string) (EntityID, error) {
+	if len(s) == 0 || !strings.Contains(s, "wikidata.org/reference/") {
+		return EntityID(""), errors.Errorf("invalid wikidata reference uri: %s", s)
+	}
+	return EntityID(s[strings.LastIndex(s, "/")+1:]), nil
+}
+
+// reference is the provenance of a single statement.
+type reference struct {
+	ID EntityID
+	// Statement is the ID of the statement the reference supports.
+	Statement EntityID
+	StatedIn  *entity
+	URL       string
+	Retrieved string
+}
+
+func (Ω *reference) Facets() []Facet {
+	f := []Facet{}
+	if Ω.StatedIn != nil {
+		f = append(f,
+			Facet{Key: "stated_in", Value: Ω.StatedIn.Name, Type: SchemaTypeString},
+			Facet{Key: "stated_in_id", Value: string(Ω.StatedIn.ID), Type: SchemaTypeString},
+		)
+	}
+	if Ω.URL != "" {
+		f = append(f, Facet{Key: "reference_url", Value: Ω.URL, Type: SchemaTypeString})
+	}
+	if Ω.Retrieved != "" {
+		f = append(f, Facet{Key: "retrieved", Value: Ω.Retrieved, Type: SchemaTypeDateTime})
+	}
+	return f
+}
+
+// Write creates the reference node and connects it to the node of the statement it supports. The
+// statement node is connected to the object entity, and holds the claim predicate and the value, so
+// that the reference is tied to the value rather than to the entity.
+func (Ω *reference) Write(object *entity, v *parsedValue, e *emitter) error {
+	if err := e.EntityNode(EntityNode{ID: Ω.Statement, Type: predicateStatementType}); err != nil {
+		return err
+	}
+	if err := e.Edge(Edge{Object: object.ID, Predicate: predicateStatement, EntityType: object.Type, Subject: Ω.Statement}); err != nil {
+		return err
+	}
+	if err := e.Feature(Feature{
+		Entity:     Ω.Statement,
+		Predicate:  predicateClaim,
+		EntityType: predicateStatementType,
+		Value:      string(v.predicate),
+		Type:       SchemaTypeString,
+	}, false); err != nil {
+		return err
+	}
+	if v.entityValue != nil {
+		if err := e.Edge(Edge{Object: Ω.Statement, Predicate: predicateValueEntity, EntityType: predicateStatementType, Subject: v.entityValue.ID}); err != nil {
+			return err
+		}
+	} else if v.stringValue != "" {
+		// Values are kept as written, whatever their type, so that the predicate has a single type.
+		if err := e.Feature(Feature{
+			Entity:     Ω.Statement,
+			Predicate:  predicateValue,
+			EntityType: predicateStatementType,
+			Value:      v.stringValue,
+			Lang:       v.lang,
+			Type:       SchemaTypeString,
+		}, v.lang != ""); err != nil {
+			return err
+		}
+	}
+
+	if err := e.EntityNode(EntityNode{ID: Ω.ID, Type: predicateReferenceType}); err != nil {
+		return err
+	}
+	if Ω.StatedIn != nil {
+		if err := Ω.StatedIn.Write(e); err != nil {
+			return err
+		}
+		if err := e.Edge(Edge{Object: Ω.ID, Predicate: predicateStatedIn, EntityType: predicateReferenceType, Subject: Ω.StatedIn.ID}); err != nil {
+			return err
+		}
+	}
+	if Ω.URL != "" {
+		if err := e.Feature(Feature{
+			Entity:     Ω.ID,
+			Predicate:  predicateReferenceURL,
+			EntityType: predicateReferenceType,
+			Value:      Ω.URL,
+			Type:       SchemaTypeString,
+		}, false); err != nil {
+			return err
+		}
+	}
+	if Ω.Retrieved != "" {
+		if err := e.Feature(Feature{
+			Entity:     Ω.ID,
+			Predicate:  predicateRetrieved,
+			EntityType: predicateReferenceType,
+			Value:      Ω.Retrieved,
+			Type:       SchemaTypeDateTime,
+		}, false); err != nil {
+			return err
+		}
+	}
+	return e.Edge(Edge{
+		Object:     Ω.Statement,
+		Predicate:  predicateReference,
+		EntityType: predicateStatementType,
+		Subject:    Ω.ID,
+	})
+}
+
 // Note that it is ok for string Value to be empty if it is an Entity type.
 type parsedValue struct {
 	stringValue string
 	entityValue *entity
-	predicate   predicate
-	schemaType  schemaType
+	predicate   Predicate
+	schemaType  SchemaType
+	facets      []Facet
+	reference   *reference
+	// lang is the language tag of monolingual text.
+	lang     string
+	property property
+}
+
+// key identifies the value among the values of its predicate.
+func (Ω *parsedValue) key() string {
+	if Ω.entityValue != nil {
+		return string(Ω.entityValue.ID)
+	}
+	return Ω.stringValue + "@" + Ω.lang
 }
 
-func (Ω *parsedValue) Write(object *entity, rdf *rdf, schema *schema) error {
+func (Ω *parsedValue) Write(object *entity, references ReferenceMode, e *emitter) error {
+
+	e.property(Ω.property)
+
+	f := append([]Facet{}, Ω.facets...)
+	var r *valueReference
+	if references == ReferenceModeFacets {
+		// The emitter writes the value once, with the reference of the lowest ID.
+		r = &valueReference{Value: string(object.ID) + " " + string(Ω.predicate) + " " + Ω.key()}
+	}
+	if Ω.reference != nil {
+		switch references {
+		case ReferenceModeFacets:
+			r.Reference = Ω.reference.ID
+			f = append(f, Ω.reference.Facets()...)
+		case ReferenceModeNodes:
+			if err := Ω.reference.Write(object, Ω, e); err != nil {
+				return err
+			}
+		}
+	}
 
 	if Ω.entityValue != nil {
-		if err := Ω.entityValue.Write(rdf, schema); err != nil {
+		if err := Ω.entityValue.Write(e); err != nil {
 			return err
 		}
-		if err := rdf.WriteEdge(object.ID, Ω.predicate, Ω.entityValue.ID); err != nil {
-			return err
+		return e.referencedEdge(Edge{
+			Object:     object.ID,
+			Predicate:  Ω.predicate,
+			Property:   Ω.property.ID,
+			EntityType: object.Type,
+			Subject:    Ω.entityValue.ID,
+			Facets:     f,
+		}, r)
+	}
+
+	if Ω.schemaType == SchemaTypeDefault {
+		// A type predicate, such as one from a subclass of property, has no value.
+		return e.EntityNode(EntityNode{ID: object.ID, Type: Ω.predicate})
+	}
+
+	return e.referencedFeature(Feature{
+		Entity:     object.ID,
+		Predicate:  Ω.predicate,
+		Property:   Ω.property.ID,
+		EntityType: object.Type,
+		Value:      Ω.stringValue,
+		Lang:       Ω.lang,
+		Type:       Ω.schemaType,
+		Facets:     f,
+	}, Ω.lang != "", r)
+}
+
+// quantity is a numeric value with optional bounds and a unit label. Amounts are kept as the
+// decimal strings Wikidata writes, so that large integers do not lose precision.
+type quantity struct {
+	Amount     string
+	UpperBound *string
+	LowerBound *string
+	Unit       string
+	// Integer is true for properties Wikidata constrains to integer values, such as counts, so that
+	// every value of a property has the same type.
+	Integer bool
+}
+
+func (Ω *quantity) SchemaType() SchemaType {
+	if Ω.Integer {
+		return SchemaTypeInt
+	}
+	return SchemaTypeFloat
+}
+
+func (Ω *quantity) Facets() []Facet {
+	f := []Facet{}
+	if Ω.Unit != "" {
+		f = append(f, Facet{Key: "unit", Value: Ω.Unit, Type: SchemaTypeString})
+	}
+	for _, bound := range []struct {
+		key   string
+		value *string
+	}{{"upper_bound", Ω.UpperBound}, {"lower_bound", Ω.LowerBound}} {
+		if bound.value == nil {
+			continue
 		}
-	} else {
-		if err := rdf.WriteFeature(object.ID, Ω.predicate, Ω.stringValue); err != nil {
-			return err
+		// A bound of an integer amount can still be fractional, and facets are typed per value.
+		t := SchemaTypeFloat
+		if _, ok := integerAmount(*bound.value); ok {
+			t = SchemaTypeInt
 		}
+		f = append(f, Facet{Key: bound.key, Value: *bound.value, Type: t})
+	}
+	return f
+}
+
+// date is a Wikidata time value, with the precision it was recorded at. Precision counts from 0
+// for billions of years through 9 for a year and 11 for a day to 14 for a second.
+type date struct {
+	Value     string
+	Precision *int
+}
+
+// Year returns the year of the date, which is negative before year zero.
+func (Ω *date) Year() (string, bool) {
+	fields := strings.Split(Ω.Value, "-")
+	if len(fields) < 2 {
+		return "", false
+	}
+	if fields[0] == "" {
+		// Means it was a negative year.
+		return "-" + fields[1], true
 	}
+	return fields[0], true
+}
 
-	return schema.Write(Ω.predicate, Ω.schemaType)
+func (Ω *date) Facets() []Facet {
+	f := []Facet{{Key: "date", Value: Ω.Value, Type: SchemaTypeString}}
+	if Ω.Precision != nil {
+		f = append(f, Facet{Key: "precision", Value: strconv.Itoa(*Ω.Precision), Type: SchemaTypeInt})
+	}
+	return f
 }
 
 type wikibaseOntology string
@@ -98,7 +473,8 @@ var knownWikibaseOntologies = map[wikibaseOntology]struct{}{
 	"http://wikiba.se/ontology#GlobeCoordinate": {},
 }
 
-type predicate string
+// Predicate is a normalized Dgraph predicate, prefixed by its predicateType.
+type Predicate string
 type predicateType string
 
 const (
@@ -109,75 +485,65 @@ const (
 
 var alphaNumeric = regexp.MustCompile("[^a-zA-Z0-9]+")
 
-func newPredicate(pType predicateType, s string) predicate {
-	s = strings.ToLower(s)
-	s = alphaNumeric.ReplaceAllString(s, "_")
-	s = string(pType) + s
-	return predicate(s)
-}
-
-type schemaType string
+// validPredicate restricts predicates to characters that need no escaping within an N-Triples IRI.
+// Leading and trailing underscores are kept, so that labels such as "x (obsolete)" keep the
+// predicates they have always had.
+var validPredicate = regexp.MustCompile("^[eft]_(P[0-9]+|[a-z0-9_]*[a-z0-9][a-z0-9_]*)$")
 
-const (
-	schemaTypeGeo     = schemaType("geo")
-	schemaTypeInt     = schemaType("int")
-	schemaTypeFloat   = schemaType("float")
-	schemaTypeString  = schemaType("string")
-	schemaTypeDefault = schemaType("default")
-	schemaTypeBool    = schemaType("bool")
-	schemaTypeUID     = schemaType("uid")
-)
-
-type schema struct {
-	m      *sync.Map
-	writer io.Writer
-}
+// propertyID matches a Wikidata property ID, such as P710.
+var propertyID = regexp.MustCompile("^P[0-9]+$")
 
-func (Ω *schema) Write(p predicate, t schemaType) error {
-	line := fmt.Sprintf("%s: %s .\n", p, t)
-	if t == schemaTypeUID {
-		line = fmt.Sprintf("%s: %s @reverse .\n", p, t)
+// newPredicate forms a predicate from a label, or from a property ID, which is kept as it is
+// so that predicates keyed on property IDs read like e_P710.
+func newPredicate(pType predicateType, s string) (Predicate, error) {
+	name := s
+	if !propertyID.MatchString(s) {
+		name = strings.ToLower(s)
+		name = alphaNumeric.ReplaceAllString(name, "_")
 	}
-	if _, ok := Ω.m.LoadOrStore(line, struct{}{}); !ok {
-		if _, err := Ω.writer.Write([]byte(line)); err != nil {
-			return errors.Wrapf(err, "could not write [%s, %s]", p, t)
-		}
+	p := Predicate(string(pType) + name)
+	if !validPredicate.MatchString(string(p)) {
+		return "", errors.Errorf("could not form a valid predicate from [%s]", s)
 	}
-	return nil
-
-}
-
-type rdf struct {
-	m      *sync.Map
-	writer io.Writer
+	return p, nil
 }
 
-func (Ω *rdf) WriteFeature(entityID entityID, predicate predicate, value string) error {
-	line := fmt.Sprintf(
-		`_:%s <%s> "%s" .`,
-		entityID,
-		predicate,
-		value,
-	) + "\n"
-	if _, ok := Ω.m.LoadOrStore(line, 1); !ok {
-		if _, err := Ω.writer.Write([]byte(line)); err != nil {
-			return errors.Wrapf(err, "could not write [%s] [%s] [%s]", entityID, predicate, value)
-		}
+func mustPredicate(pType predicateType, s string) Predicate {
+	p, err := newPredicate(pType, s)
+	if err != nil {
+		panic(err)
 	}
-	return nil
+	return p
 }
 
-func (Ω *rdf) WriteEdge(object entityID, predicate predicate, subject entityID) error {
-	line := fmt.Sprintf(
-		"_:%s <%s> _:%s .\n",
-		object,
-		predicate,
-		subject,
-	)
-	if _, ok := Ω.m.LoadOrStore(line, 1); !ok {
-		if _, err := Ω.writer.Write([]byte(line)); err != nil {
-			return errors.Wrapf(err, "could not write [%s, %s, %s]", object, predicate, subject)
-		}
-	}
-	return nil
-}
+var (
+	PredicateLabel         = mustPredicate(predicateFeature, "label")
+	PredicateDescription   = mustPredicate(predicateFeature, "description")
+	PredicateAlias         = mustPredicate(predicateFeature, "alias")
+	predicateReferenceType = mustPredicate(predicateEntityType, "reference")
+	predicateReference     = mustPredicate(predicateEdge, "reference")
+	predicateStatementType = mustPredicate(predicateEntityType, "statement")
+	predicateStatement     = mustPredicate(predicateEdge, "statement")
+	predicateClaim         = mustPredicate(predicateFeature, "claim")
+	predicateValue         = mustPredicate(predicateFeature, "value")
+	predicateValueEntity   = mustPredicate(predicateEdge, "value")
+	predicateStatedIn      = mustPredicate(predicateEdge, "stated in")
+	predicateReferenceURL  = mustPredicate(predicateFeature, "reference URL")
+	predicateRetrieved     = mustPredicate(predicateFeature, "retrieved")
+)
+
+// SchemaType is the Dgraph scalar type of a predicate.
+type SchemaType string
+
+const (
+	SchemaTypeGeo    = SchemaType("geo")
+	SchemaTypeInt    = SchemaType("int")
+	SchemaTypeFloat  = SchemaType("float")
+	SchemaTypeString = SchemaType("string")
+	// SchemaTypeStringList holds multiple string values for a single node.
+	SchemaTypeStringList = SchemaType("[string]")
+	SchemaTypeDefault    = SchemaType("default")
+	SchemaTypeBool       = SchemaType("bool")
+	SchemaTypeUID        = SchemaType("uid")
+	SchemaTypeDateTime   = SchemaType("datetime")
+)
@@ -7,6 +7,8 @@ package parse
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/heindl/wikivents/fetch/endpoint"
@@ -15,17 +17,34 @@ import (
 )
 
 type parser struct {
-	binding *endpoint.Binding
+	binding    *endpoint.Binding
+	predicates PredicateMode
+	// dates adds the date and precision facets to time values.
+	dates bool
+	// dropped is why the binding was ignored, if it was.
+	dropped string
 }
 
-func escapeFeatureValue(v string) string {
-	return strings.Replace(v, `"`, "'", -1)
+// drop records why the binding is being ignored.
+func (Ω *parser) drop(reason string) {
+	Ω.dropped = reason
+}
+
+var languageTag = regexp.MustCompile("^[a-zA-Z]+(-[a-zA-Z0-9]+)*$")
+
+// lang returns the language tag of the key if it is safe to write in RDF, or an empty string.
+func (Ω *parser) lang(key string) string {
+	if lang := Ω.binding.Lang(key); languageTag.MatchString(lang) {
+		return lang
+	}
+	return ""
 }
 
 func (Ω *parser) Entity(key string) (*entity, error) {
 
 	if Ω.binding.Type(key) == "bnode" {
 		logrus.Debugf("received bNode entity: %s", key)
+		Ω.drop("blank node entity")
 		return nil, nil
 	}
 
@@ -44,20 +63,186 @@ func (Ω *parser) Entity(key string) (*entity, error) {
 	if err != nil {
 		// Restrict strongly toward well formed entities, but no need to crash the system.
 		logrus.Warnf("received incomplete entity: %v", Ω.binding.Values())
+		Ω.drop("incomplete entity")
 		return nil, nil
 	}
 
 	// Within subject entities, these are often missing so just need to ignore.
-	e.Type = Ω.binding.String(key + "InstanceOfLabel")
+	if instanceOf := Ω.binding.String(key + "InstanceOfLabel"); instanceOf != "" {
+		if e.Type, err = newPredicate(predicateEntityType, instanceOf); err != nil {
+			logrus.Debugf("ignoring entity type: %v", err)
+		}
+	}
+	e.NameLang = Ω.lang(key + "Label")
+	e.Description = strings.TrimSpace(Ω.binding.String(key + "Description"))
+	if e.Description != "" {
+		e.DescriptionLang = Ω.lang(key + "Description")
+	}
 	return e, nil
 }
 
+// IsTerm is true for bindings from the terms query rather than the entity query.
+func (Ω *parser) IsTerm() bool {
+	return Ω.binding.String("termEntity") != ""
+}
+
+// Term parses a binding that holds an entity label, description or alias in a single language.
+func (Ω *parser) Term() (*term, error) {
+
+	id, err := newEntityID(Ω.binding.String("termEntity"))
+	if err != nil {
+		return nil, err
+	}
+
+	value := strings.TrimSpace(Ω.binding.String("term"))
+	lang := Ω.lang("term")
+	if value == "" || lang == "" {
+		logrus.Debugf("received incomplete term: %v", Ω.binding.Values())
+		Ω.drop("incomplete term")
+		return nil, nil
+	}
+
+	t := termType(Ω.binding.String("termType"))
+	switch t {
+	case termTypeLabel, termTypeDescription, termTypeAlias:
+	default:
+		return nil, errors.Errorf("unknown term type [%s]", t)
+	}
+
+	return &term{
+		Entity: id,
+		Type:   t,
+		Value:  value,
+		Lang:   lang,
+	}, nil
+}
+
+// Reference returns the statement reference attached to the binding, if any.
+func (Ω *parser) Reference() (*reference, error) {
+
+	uri := Ω.binding.String("reference")
+	if uri == "" {
+		return nil, nil
+	}
+
+	id, err := newReferenceID(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	statement, err := newStatementID(Ω.binding.String("statement"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "binding has reference [%s] without a valid statement", uri)
+	}
+
+	r := &reference{
+		ID:        id,
+		Statement: statement,
+		URL:       Ω.binding.String("referenceURL"),
+		Retrieved: Ω.binding.Date("referenceRetrieved"),
+	}
+
+	if Ω.binding.String("referenceStatedIn") != "" {
+		r.StatedIn, err = Ω.Entity("referenceStatedIn")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.StatedIn == nil && r.URL == "" && r.Retrieved == "" {
+		return nil, nil
+	}
+
+	return r, nil
+}
+
+// The unit entity Wikidata assigns to dimensionless quantities.
+const unitOne = "http://www.wikidata.org/entity/Q199"
+
+var (
+	decimalAmount = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+	integerZeros  = regexp.MustCompile(`\.0+$`)
+)
+
+// normalAmount reads a Wikidata decimal amount, such as +2.5, without its leading plus sign.
+func normalAmount(amount string) (string, bool) {
+	if !decimalAmount.MatchString(amount) {
+		return "", false
+	}
+	return strings.TrimPrefix(amount, "+"), true
+}
+
+// integerAmount reads the decimal amount as an integer, if it is whole and fits the int64 of a Dgraph int.
+func integerAmount(amount string) (string, bool) {
+	s, ok := normalAmount(amount)
+	if !ok {
+		return "", false
+	}
+	s = integerZeros.ReplaceAllString(s, "")
+	if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// quantity reads the amount and its bounds, or returns nil if the amount does not fit the type of
+// its property.
+func (Ω *parser) quantity(amount string) (*quantity, error) {
+
+	q := &quantity{Integer: Ω.binding.String("quantityIntegerConstraint") != ""}
+
+	var ok bool
+	if q.Integer {
+		q.Amount, ok = integerAmount(amount)
+		if !ok {
+			Ω.drop("non-integer quantity")
+			return nil, nil
+		}
+	} else if q.Amount, ok = normalAmount(amount); !ok {
+		return nil, errors.Errorf("could not parse quantity amount [%s]", amount)
+	}
+
+	for key, bound := range map[string]**string{
+		"quantityUpperBound": &q.UpperBound,
+		"quantityLowerBound": &q.LowerBound,
+	} {
+		s := Ω.binding.String(key)
+		if s == "" {
+			continue
+		}
+		v, ok := normalAmount(s)
+		if !ok {
+			return nil, errors.Errorf("could not parse quantity bound [%s]", s)
+		}
+		if i, ok := integerAmount(s); ok {
+			v = i
+		}
+		*bound = &v
+	}
+
+	if unit := Ω.binding.String("quantityUnit"); unit != "" && unit != unitOne {
+		q.Unit = Ω.binding.String("quantityUnitLabel")
+	}
+
+	return q, nil
+}
+
+func (Ω *parser) date(value string) *date {
+	d := &date{Value: value}
+	if precision, err := Ω.binding.MustInt("timePrecision"); err == nil {
+		d.Precision = &precision
+	}
+	return d
+}
+
 func (Ω *parser) label() (string, error) {
 	label, err := Ω.binding.MustString("propertyLabel")
 	if err != nil {
 		return "", errors.Wrap(err, "binding missing propertyLabel, though all should have one")
 	}
 	if label == "instance of" {
+		// Written as the type of the entity instead.
+		Ω.drop("instance of")
 		return "", nil
 	}
 	return label, nil
@@ -75,7 +260,40 @@ func (Ω *parser) ontology() (wikibaseOntology, error) {
 	return wikibaseOntology(ontology), nil
 }
 
+// property reads the ID, label and datatype of the property, with the ID empty if it was not selected.
+func (Ω *parser) property() (property, error) {
+	p := property{
+		Label:    Ω.binding.String("propertyLabel"),
+		Datatype: strings.TrimPrefix(Ω.binding.String("wikibaseType"), "http://wikiba.se/ontology#"),
+	}
+	uri := Ω.binding.String("property")
+	if uri == "" {
+		if Ω.predicates == PredicateModeID {
+			return p, errors.Errorf("binding missing property, which is required to name predicates by id [%s]", p.Label)
+		}
+		return p, nil
+	}
+	p.ID = uri[strings.LastIndex(uri, "/")+1:]
+	if !propertyID.MatchString(p.ID) {
+		return p, errors.Errorf("invalid wikidata property uri: %s", uri)
+	}
+	return p, nil
+}
+
+// Value parses the property value and attaches its statement reference.
 func (Ω *parser) Value() (*parsedValue, error) {
+	v, err := Ω.value()
+	if err != nil || v == nil {
+		return nil, err
+	}
+	v.reference, err = Ω.Reference()
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (Ω *parser) value() (*parsedValue, error) {
 
 	label, err := Ω.label()
 	if err != nil || label == "" {
@@ -88,12 +306,34 @@ func (Ω *parser) Value() (*parsedValue, error) {
 	}
 
 	if label == "subclass of" {
+		pred, err := newPredicate(predicateEntityType, stringVal)
+		if err != nil {
+			logrus.Debugf("ignoring subclass: %v", err)
+			Ω.drop("invalid subclass")
+			return nil, nil
+		}
 		return &parsedValue{
-			predicate:  newPredicate(predicateEntityType, escapeFeatureValue(stringVal)),
-			schemaType: schemaTypeDefault,
+			predicate:  pred,
+			schemaType: SchemaTypeDefault,
 		}, nil
 	}
 
+	prop, err := Ω.property()
+	if err != nil {
+		return nil, err
+	}
+	name := label
+	if Ω.predicates == PredicateModeID {
+		name = prop.ID
+	}
+
+	// Predicate prefixes do not affect validity, so the remaining mustPredicate calls are safe.
+	if _, err := newPredicate(predicateFeature, name); err != nil {
+		logrus.Warnf("ignoring property: %v", err)
+		Ω.drop("invalid property")
+		return nil, nil
+	}
+
 	ontology, err := Ω.ontology()
 	if err != nil || ontology == "" {
 		return nil, err
@@ -101,6 +341,7 @@ func (Ω *parser) Value() (*parsedValue, error) {
 
 	switch ontology {
 	case "http://wikiba.se/ontology#CommonsMedia", "http://wikiba.se/ontology#ExternalId", "http://wikiba.se/ontology#Url":
+		Ω.drop("ignored datatype")
 		return nil, nil
 	case "http://wikiba.se/ontology#WikibaseItem":
 		subject, err := Ω.Entity("value")
@@ -109,8 +350,9 @@ func (Ω *parser) Value() (*parsedValue, error) {
 		}
 		return &parsedValue{
 			entityValue: subject,
-			predicate:   newPredicate(predicateEdge, label),
-			schemaType:  schemaTypeUID,
+			predicate:   mustPredicate(predicateEdge, name),
+			property:    prop,
+			schemaType:  SchemaTypeUID,
 		}, nil
 
 	case "http://wikiba.se/ontology#GlobeCoordinate":
@@ -120,33 +362,55 @@ func (Ω *parser) Value() (*parsedValue, error) {
 		}
 		gj := fmt.Sprintf(`{"type": "Point","coordinates":[%f,%f]}`, lng, lat)
 		return &parsedValue{
-			stringValue: escapeFeatureValue(gj),
-			predicate:   newPredicate(predicateFeature, label),
-			schemaType:  schemaTypeGeo,
+			stringValue: gj,
+			predicate:   mustPredicate(predicateFeature, name),
+			property:    prop,
+			schemaType:  SchemaTypeGeo,
 		}, nil
 	case "http://wikiba.se/ontology#Time":
-		dateFields := strings.Split(stringVal, "-")
-		if len(dateFields) < 2 {
+		d := Ω.date(stringVal)
+		year, ok := d.Year()
+		if !ok {
+			Ω.drop("invalid date")
 			return nil, nil
 		}
-		year := dateFields[0]
-		if year == "" {
-			// Means it was a negative year.
-			year = "-" + dateFields[1]
+		v := &parsedValue{
+			stringValue: year,
+			predicate:   mustPredicate(predicateFeature, name),
+			property:    prop,
+			schemaType:  SchemaTypeInt,
+		}
+		if Ω.dates {
+			v.facets = d.Facets()
+		}
+		return v, nil
+	case "http://wikiba.se/ontology#Quantity":
+		q, err := Ω.quantity(stringVal)
+		if err != nil || q == nil {
+			return nil, err
 		}
 		return &parsedValue{
-			stringValue: year,
-			predicate:   newPredicate(predicateFeature, label),
-			schemaType:  schemaTypeInt,
+			stringValue: q.Amount,
+			predicate:   mustPredicate(predicateFeature, name),
+			property:    prop,
+			schemaType:  q.SchemaType(),
+			facets:      q.Facets(),
+		}, nil
+	case "http://wikiba.se/ontology#Monolingualtext":
+		return &parsedValue{
+			stringValue: stringVal,
+			predicate:   mustPredicate(predicateFeature, name),
+			property:    prop,
+			schemaType:  SchemaTypeString,
+			lang:        Ω.lang("value"),
 		}, nil
 	default:
-		// "http://wikiba.se/ontology#String",
-		// "http://wikiba.se/ontology#Quantity",
-		// "http://wikiba.se/ontology#Monolingualtext"
+		// "http://wikiba.se/ontology#String"
 		return &parsedValue{
-			stringValue: escapeFeatureValue(stringVal),
-			predicate:   newPredicate(predicateFeature, label),
-			schemaType:  schemaTypeString,
+			stringValue: stringVal,
+			predicate:   mustPredicate(predicateFeature, name),
+			property:    prop,
+			schemaType:  SchemaTypeString,
 		}, nil
 	}
 }
@@ -9,10 +9,15 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/heindl/wikivents/fetch/endpoint"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,7 +37,7 @@ func TestParser(t *testing.T) {
 	rdfWriter := bufio.NewWriter(rdfBuffer)
 	schemaWriter := bufio.NewWriter(schemaBuffer)
 
-	writer := NewWriter(rdfWriter, schemaWriter)
+	writer := NewWriter(NewRDFSink(rdfWriter, NewDgraphSchema(schemaWriter, nil), NodeModeBlank), Options{})
 
 	// Run concurrently to check Write safety.
 	// TODO: Repair wierd issue that makes the result length fluxuate.
@@ -47,6 +52,7 @@ func TestParser(t *testing.T) {
 	//return nil
 	//})
 	//assert.NoError(t, eg.Wait())
+	assert.NoError(t, writer.Close())
 
 	assert.NoError(t, rdfWriter.Flush())
 	assert.NoError(t, schemaWriter.Flush())
@@ -54,3 +60,780 @@ func TestParser(t *testing.T) {
 	assert.Equal(t, 5957, len(bytes.Split(rdfBuffer.Bytes(), []byte("\n"))))
 	assert.Equal(t, 698, len(bytes.Split(schemaBuffer.Bytes(), []byte("\n"))))
 }
+
+func TestConcurrentWrites(t *testing.T) {
+
+	// Batches are parsed concurrently, so the sink must serialize writes to a buffered writer.
+	rdfBuffer := bytes.NewBuffer([]byte{})
+	rdfWriter := bufio.NewWriterSize(rdfBuffer, 16)
+	writer := NewWriter(NewRDFSink(rdfWriter, NewDgraphSchema(ioutil.Discard, nil), NodeModeBlank), Options{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, writer.ParseBinding(&endpoint.Binding{
+				"object":        {Type: "uri", Value: fmt.Sprintf("http://www.wikidata.org/entity/Q%d", i)},
+				"objectLabel":   {Type: "literal", Value: fmt.Sprintf("Battle %d", i)},
+				"propertyLabel": {Type: "literal", Value: "point in time"},
+				"wikibaseType":  {Type: "uri", Value: "http://wikiba.se/ontology#Time"},
+				"value":         {Type: "literal", Value: "1066-10-14T00:00:00Z"},
+			}))
+		}(i)
+	}
+	wg.Wait()
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, rdfWriter.Flush())
+
+	lines := strings.Split(strings.TrimSuffix(rdfBuffer.String(), "\n"), "\n")
+	assert.Len(t, lines, 100)
+	for _, line := range lines {
+		assert.Regexp(t, `^_:Q\d+ <f_(label|point_in_time)> "[^"]+"( \(.*\))? \.$`, line)
+	}
+}
+
+func TestReferenceFacets(t *testing.T) {
+
+	b := &endpoint.Binding{
+		"object":                 {Type: "uri", Value: "http://www.wikidata.org/entity/Q48314"},
+		"objectLabel":            {Type: "literal", Value: "Battle of Hastings"},
+		"propertyLabel":          {Type: "literal", Value: "participant"},
+		"wikibaseType":           {Type: "uri", Value: "http://wikiba.se/ontology#WikibaseItem"},
+		"value":                  {Type: "uri", Value: "http://www.wikidata.org/entity/Q102140"},
+		"valueLabel":             {Type: "literal", Value: "William the Conqueror"},
+		"statement":              {Type: "uri", Value: "http://www.wikidata.org/entity/statement/Q48314-6C9A1D1E-4F3B-4E6A-9B0E-3C0D2F1B7A21"},
+		"reference":              {Type: "uri", Value: "http://www.wikidata.org/reference/fa278ebfc458360e5aed63d5058cca83c46134f1"},
+		"referenceStatedIn":      {Type: "uri", Value: "http://www.wikidata.org/entity/Q5375741"},
+		"referenceStatedInLabel": {Type: "literal", Value: "Encyclopædia Britannica Online"},
+		"referenceRetrieved":     {Type: "literal", Value: "2018-10-15T00:00:00Z"},
+	}
+
+	for mode, expected := range map[ReferenceMode][]string{
+		ReferenceModeNone:   {`_:Q48314 <e_participant> _:Q102140 .`},
+		ReferenceModeFacets: {`_:Q48314 <e_participant> _:Q102140 (stated_in="Encyclopædia Britannica Online", stated_in_id="Q5375741", retrieved=2018-10-15T00:00:00Z) .`},
+		// The reference is attached to the statement, which holds the claim and value it supports.
+		ReferenceModeNodes: {
+			`_:Q48314 <e_participant> _:Q102140 .`,
+			`_:Q48314 <e_statement> _:Q48314-6C9A1D1E-4F3B-4E6A-9B0E-3C0D2F1B7A21 .`,
+			`_:Q48314-6C9A1D1E-4F3B-4E6A-9B0E-3C0D2F1B7A21 <f_claim> "e_participant" .`,
+			`_:Q48314-6C9A1D1E-4F3B-4E6A-9B0E-3C0D2F1B7A21 <e_value> _:Q102140 .`,
+			`_:Q48314-6C9A1D1E-4F3B-4E6A-9B0E-3C0D2F1B7A21 <e_reference> _:fa278ebfc458360e5aed63d5058cca83c46134f1 .`,
+		},
+	} {
+		rdfBuffer := bytes.NewBuffer([]byte{})
+		schemaBuffer := bytes.NewBuffer([]byte{})
+		writer := NewWriter(NewRDFSink(rdfBuffer, NewDgraphSchema(schemaBuffer, nil), NodeModeBlank), Options{References: mode})
+		assert.NoError(t, writer.ParseBinding(b))
+		assert.NoError(t, writer.Close())
+		for _, line := range expected {
+			assert.Contains(t, rdfBuffer.String(), line+"\n")
+		}
+	}
+
+	// A second reference of the same statement is only kept as a node.
+	second := endpoint.Binding{}
+	for k, v := range *b {
+		second[k] = v
+	}
+	for key, value := range map[string]string{
+		"reference":              "http://www.wikidata.org/reference/0e5a2e4cb9bd7b3c0a8e5b1c8f3d0b5e8c6a4f21",
+		"referenceStatedIn":      "http://www.wikidata.org/entity/Q36578",
+		"referenceStatedInLabel": "Integrated Authority File",
+	} {
+		v := second[key]
+		v.Value = value
+		second[key] = v
+	}
+	for mode, count := range map[ReferenceMode]int{ReferenceModeFacets: 0, ReferenceModeNodes: 2} {
+		// The reference kept as facets is the one with the lowest ID, whichever arrives first.
+		outputs := []string{}
+		for _, bindings := range [][]*endpoint.Binding{{b, &second, b}, {&second, b}} {
+			rdfBuffer := bytes.NewBuffer([]byte{})
+			writer := NewWriter(NewRDFSink(rdfBuffer, NewDgraphSchema(ioutil.Discard, nil), NodeModeBlank), Options{References: mode})
+			for _, binding := range bindings {
+				assert.NoError(t, writer.ParseBinding(binding))
+			}
+			assert.NoError(t, writer.Close())
+			assert.Equal(t, 1, strings.Count(rdfBuffer.String(), "<e_participant>"), string(mode))
+			assert.Equal(t, count, strings.Count(rdfBuffer.String(), "<e_reference>"), string(mode))
+			outputs = append(outputs, rdfBuffer.String())
+		}
+		assert.Equal(t, outputs[0], outputs[1], string(mode))
+		if mode == ReferenceModeFacets {
+			assert.Contains(t, outputs[0], `(stated_in="Integrated Authority File", stated_in_id="Q36578", retrieved=2018-10-15T00:00:00Z) .`)
+		}
+	}
+}
+
+func TestQuantity(t *testing.T) {
+
+	for _, quantity := range []struct {
+		binding  *endpoint.Binding
+		expected string
+		schema   string
+	}{
+		{
+			binding: &endpoint.Binding{
+				"object":                    {Type: "uri", Value: "http://www.wikidata.org/entity/Q48314"},
+				"objectLabel":               {Type: "literal", Value: "Battle of Hastings"},
+				"propertyLabel":             {Type: "literal", Value: "number of deaths"},
+				"wikibaseType":              {Type: "uri", Value: "http://wikiba.se/ontology#Quantity"},
+				"value":                     {Type: "literal", Value: "7000"},
+				"quantityUnit":              {Type: "uri", Value: "http://www.wikidata.org/entity/Q199"},
+				"quantityUpperBound":        {Type: "literal", Value: "12000"},
+				"quantityIntegerConstraint": {Type: "uri", Value: "http://www.wikidata.org/entity/statement/P1120-6f3e0a3b"},
+			},
+			expected: `_:Q48314 <f_number_of_deaths> "7000" (upper_bound=12000) .`,
+			schema:   "f_number_of_deaths: int @index(int) .",
+		},
+		{
+			binding: &endpoint.Binding{
+				"object":            {Type: "uri", Value: "http://www.wikidata.org/entity/Q48314"},
+				"objectLabel":       {Type: "literal", Value: "Battle of Hastings"},
+				"propertyLabel":     {Type: "literal", Value: "area"},
+				"wikibaseType":      {Type: "uri", Value: "http://wikiba.se/ontology#Quantity"},
+				"value":             {Type: "literal", Value: "+2.5"},
+				"quantityUnit":      {Type: "uri", Value: "http://www.wikidata.org/entity/Q712226"},
+				"quantityUnitLabel": {Type: "literal", Value: "square kilometre"},
+			},
+			expected: `_:Q48314 <f_area> "2.5" (unit="square kilometre") .`,
+			schema:   "f_area: float @index(float) .",
+		},
+		{
+			binding: &endpoint.Binding{
+				"object":                    {Type: "uri", Value: "http://www.wikidata.org/entity/Q2"},
+				"objectLabel":               {Type: "literal", Value: "Earth"},
+				"propertyLabel":             {Type: "literal", Value: "population"},
+				"wikibaseType":              {Type: "uri", Value: "http://wikiba.se/ontology#Quantity"},
+				"value":                     {Type: "literal", Value: "+9007199254740993"},
+				"quantityUnit":              {Type: "uri", Value: "http://www.wikidata.org/entity/Q199"},
+				"quantityLowerBound":        {Type: "literal", Value: "+9007199254740992.5"},
+				"quantityIntegerConstraint": {Type: "uri", Value: "http://www.wikidata.org/entity/statement/P1082-c8e0f7a1"},
+			},
+			expected: `_:Q2 <f_population> "9007199254740993" (lower_bound=9007199254740992.5) .`,
+			schema:   "f_population: int @index(int) .",
+		},
+		{
+			binding: &endpoint.Binding{
+				"object":                    {Type: "uri", Value: "http://www.wikidata.org/entity/Q2"},
+				"objectLabel":               {Type: "literal", Value: "Earth"},
+				"propertyLabel":             {Type: "literal", Value: "population"},
+				"wikibaseType":              {Type: "uri", Value: "http://wikiba.se/ontology#Quantity"},
+				"value":                     {Type: "literal", Value: "+99999999999999999999"},
+				"quantityIntegerConstraint": {Type: "uri", Value: "http://www.wikidata.org/entity/statement/P1082-c8e0f7a1"},
+			},
+		},
+	} {
+		rdfBuffer := bytes.NewBuffer([]byte{})
+		schemaBuffer := bytes.NewBuffer([]byte{})
+		writer := NewWriter(NewRDFSink(rdfBuffer, NewDgraphSchema(schemaBuffer, nil), NodeModeBlank), Options{})
+		assert.NoError(t, writer.ParseBinding(quantity.binding))
+		assert.NoError(t, writer.Close())
+		if quantity.expected == "" {
+			// An integer property drops the amounts that do not fit an int.
+			assert.NotContains(t, rdfBuffer.String(), "<f_population>")
+			continue
+		}
+		assert.Contains(t, rdfBuffer.String(), quantity.expected+"\n")
+		assert.Contains(t, schemaBuffer.String(), quantity.schema+"\n")
+	}
+}
+
+func TestDate(t *testing.T) {
+
+	for _, date := range []struct {
+		binding  *endpoint.Binding
+		expected string
+	}{
+		{
+			binding: &endpoint.Binding{
+				"object":        {Type: "uri", Value: "http://www.wikidata.org/entity/Q48314"},
+				"objectLabel":   {Type: "literal", Value: "Battle of Hastings"},
+				"propertyLabel": {Type: "literal", Value: "point in time"},
+				"wikibaseType":  {Type: "uri", Value: "http://wikiba.se/ontology#Time"},
+				"value":         {Type: "literal", Value: "1066-10-14T00:00:00Z"},
+				"timePrecision": {Type: "literal", Value: "11"},
+			},
+			expected: `_:Q48314 <f_point_in_time> "1066" (date="1066-10-14T00:00:00Z", precision=11) .`,
+		},
+		{
+			binding: &endpoint.Binding{
+				"object":        {Type: "uri", Value: "http://www.wikidata.org/entity/Q1048"},
+				"objectLabel":   {Type: "literal", Value: "Julius Caesar"},
+				"propertyLabel": {Type: "literal", Value: "date of death"},
+				"wikibaseType":  {Type: "uri", Value: "http://wikiba.se/ontology#Time"},
+				"value":         {Type: "literal", Value: "-0043-03-15T00:00:00Z"},
+			},
+			expected: `_:Q1048 <f_date_of_death> "-0043" (date="-0043-03-15T00:00:00Z") .`,
+		},
+	} {
+		rdfBuffer := bytes.NewBuffer([]byte{})
+		writer := NewWriter(NewRDFSink(rdfBuffer, NewDgraphSchema(rdfBuffer, nil), NodeModeBlank), Options{Dates: true})
+		assert.NoError(t, writer.ParseBinding(date.binding))
+		assert.NoError(t, writer.Close())
+		assert.Contains(t, rdfBuffer.String(), date.expected+"\n")
+	}
+
+	// Without the option, time values are written as their year alone, as they always were.
+	rdfBuffer := bytes.NewBuffer([]byte{})
+	writer := NewWriter(NewRDFSink(rdfBuffer, NewDgraphSchema(rdfBuffer, nil), NodeModeBlank), Options{})
+	assert.NoError(t, writer.ParseBinding(&endpoint.Binding{
+		"object":        {Type: "uri", Value: "http://www.wikidata.org/entity/Q48314"},
+		"objectLabel":   {Type: "literal", Value: "Battle of Hastings"},
+		"propertyLabel": {Type: "literal", Value: "point in time"},
+		"wikibaseType":  {Type: "uri", Value: "http://wikiba.se/ontology#Time"},
+		"value":         {Type: "literal", Value: "1066-10-14T00:00:00Z"},
+		"timePrecision": {Type: "literal", Value: "11"},
+	}))
+	assert.NoError(t, writer.Close())
+	assert.Contains(t, rdfBuffer.String(), `_:Q48314 <f_point_in_time> "1066" .`+"\n")
+}
+
+func TestMonolingualText(t *testing.T) {
+
+	b := &endpoint.Binding{
+		"object":        {Type: "uri", Value: "http://www.wikidata.org/entity/Q12560"},
+		"objectLabel":   {Type: "literal", Value: "Ottoman Empire"},
+		"propertyLabel": {Type: "literal", Value: "native label"},
+		"wikibaseType":  {Type: "uri", Value: "http://wikiba.se/ontology#Monolingualtext"},
+		"value":         {Type: "literal", Value: "Devlet-i Âliye-i Osmâniyye", Lang: "ota-latn"},
+	}
+
+	rdfBuffer := bytes.NewBuffer([]byte{})
+	schemaBuffer := bytes.NewBuffer([]byte{})
+	writer := NewWriter(NewRDFSink(rdfBuffer, NewDgraphSchema(schemaBuffer, nil), NodeModeBlank), Options{})
+	assert.NoError(t, writer.ParseBinding(b))
+	assert.NoError(t, writer.Close())
+	assert.Contains(t, rdfBuffer.String(), `_:Q12560 <f_native_label> "Devlet-i Âliye-i Osmâniyye"@ota-latn .`+"\n")
+	assert.Contains(t, schemaBuffer.String(), "f_native_label: string @index(exact, term, fulltext) @lang .\n")
+}
+
+func TestTerms(t *testing.T) {
+
+	rdfBuffer := bytes.NewBuffer([]byte{})
+	schemaBuffer := bytes.NewBuffer([]byte{})
+	writer := NewWriter(NewRDFSink(rdfBuffer, NewDgraphSchema(schemaBuffer, nil), NodeModeBlank), Options{})
+
+	for _, b := range []*endpoint.Binding{
+		{
+			"termEntity": {Type: "uri", Value: "http://www.wikidata.org/entity/Q7183"},
+			"termType":   {Type: "literal", Value: "alias"},
+			"term":       {Type: "literal", Value: "Han", Lang: "en"},
+		},
+		{
+			"termEntity": {Type: "uri", Value: "http://www.wikidata.org/entity/Q7183"},
+			"termType":   {Type: "literal", Value: "description"},
+			"term":       {Type: "literal", Value: "chinesische Dynastie", Lang: "de"},
+		},
+	} {
+		assert.NoError(t, writer.ParseBinding(b))
+	}
+	assert.NoError(t, writer.Close())
+
+	assert.Equal(t, `_:Q7183 <f_alias> "Han" .
+_:Q7183 <f_description> "chinesische Dynastie"@de .
+`, rdfBuffer.String())
+	assert.Equal(t, `f_alias: [string] @index(exact, term) .
+f_description: string @index(fulltext) @lang .
+`, schemaBuffer.String())
+}
+
+// recorder is a sink that keeps the features it receives.
+type recorder struct {
+	features []Feature
+}
+
+func (Ω *recorder) EntityNode(EntityNode) error           { return nil }
+func (Ω *recorder) Feature(f Feature) error               { Ω.features = append(Ω.features, f); return nil }
+func (Ω *recorder) Edge(Edge) error                       { return nil }
+func (Ω *recorder) PredicateSchema(PredicateSchema) error { return nil }
+func (Ω *recorder) Close() error                          { return nil }
+
+func TestLanguageTags(t *testing.T) {
+
+	rdfBuffer := bytes.NewBuffer([]byte{})
+	r := &recorder{}
+	for _, s := range []Sink{NewRDFSink(rdfBuffer, NewDgraphSchema(ioutil.Discard, nil), NodeModeBlank), r} {
+		writer := NewWriter(s, Options{})
+		for _, b := range []*endpoint.Binding{
+			{
+				"object":            {Type: "uri", Value: "http://www.wikidata.org/entity/Q7183"},
+				"objectLabel":       {Type: "literal", Value: "Han-Dynastie", Lang: "de"},
+				"objectDescription": {Type: "literal", Value: "chinesische Dynastie", Lang: "de"},
+				"propertyLabel":     {Type: "literal", Value: "instance of"},
+				"wikibaseType":      {Type: "uri", Value: "http://wikiba.se/ontology#WikibaseItem"},
+				"value":             {Type: "uri", Value: "http://www.wikidata.org/entity/Q12857432"},
+			},
+			{
+				"termEntity": {Type: "uri", Value: "http://www.wikidata.org/entity/Q7183"},
+				"termType":   {Type: "literal", Value: "alias"},
+				"term":       {Type: "literal", Value: "Han", Lang: "en"},
+			},
+		} {
+			assert.NoError(t, writer.ParseBinding(b))
+		}
+		assert.NoError(t, writer.Close())
+	}
+
+	// The fallback label and description are written untagged and tagged with the language they
+	// resolved from, and aliases are untagged, since Dgraph lists can not hold language tags.
+	assert.Equal(t, `_:Q7183 <f_alias> "Han" .
+_:Q7183 <f_description> "chinesische Dynastie" .
+_:Q7183 <f_description> "chinesische Dynastie"@de .
+_:Q7183 <f_label> "Han-Dynastie" .
+_:Q7183 <f_label> "Han-Dynastie"@de .
+`, rdfBuffer.String())
+	// Other formats receive the language of the alias.
+	assert.Contains(t, r.features, Feature{Entity: "Q7183", Predicate: PredicateAlias, Value: "Han", Lang: "en", Type: SchemaTypeStringList})
+}
+
+// parseLiteral reads the object literal of a single N-Triples line, following the ECHAR and UCHAR
+// productions of the grammar at https://www.w3.org/TR/n-triples/#n-triples-grammar.
+func parseLiteral(line string) (value, lang string, err error) {
+	start := strings.Index(line, `> "`)
+	if start == -1 {
+		return "", "", errors.Errorf("no literal in line [%s]", line)
+	}
+	b := strings.Builder{}
+	rest := line[start+3:]
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; c {
+		case '"':
+			rest = rest[i+1:]
+			if strings.HasPrefix(rest, "@") {
+				lang = rest[1:strings.Index(rest, " ")]
+				rest = rest[len(lang)+1:]
+			}
+			if rest != " .\n" {
+				return "", "", errors.Errorf("unexpected line ending [%s]", rest)
+			}
+			return b.String(), lang, nil
+		case '\n', '\r':
+			return "", "", errors.Errorf("unescaped line break in [%s]", line)
+		case '\\':
+			i++
+			if i >= len(rest) {
+				return "", "", errors.New("incomplete escape")
+			}
+			if r, ok := map[byte]rune{'t': '\t', 'b': '\b', 'n': '\n', 'r': '\r', 'f': '\f', '"': '"', '\'': '\'', '\\': '\\'}[rest[i]]; ok {
+				b.WriteRune(r)
+				continue
+			}
+			size := map[byte]int{'u': 4, 'U': 8}[rest[i]]
+			if size == 0 || i+size >= len(rest) {
+				return "", "", errors.Errorf("invalid escape in [%s]", line)
+			}
+			r, err := strconv.ParseUint(rest[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", "", errors.Wrap(err, "invalid unicode escape")
+			}
+			b.WriteRune(rune(r))
+			i += size
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", errors.Errorf("unterminated literal in [%s]", line)
+}
+
+func TestLiteralRoundTrip(t *testing.T) {
+
+	for class, value := range map[string]string{
+		"ascii":              "Battle of Hastings 1066",
+		"double quote":       `"The Conqueror"`,
+		"single quote":       "William's army",
+		"backslash":          `C:\wikivents\`,
+		"line feed":          "first\nsecond",
+		"carriage return":    "first\r\nsecond",
+		"tab":                "first\tsecond",
+		"backspace":          "first\bsecond",
+		"form feed":          "first\fsecond",
+		"null":               "first\x00second",
+		"other control":      "\x01\x1b\x1f",
+		"delete":             "first\x7fsecond",
+		"c1 control":         "first\u0085second",
+		"latin":              "Guillaume le Conquérant",
+		"cjk":                "漢朝",
+		"astral":             "𒀭 🏰",
+		"line separator":     "first\u2028second",
+		"escape like":        `\u0041 \n`,
+		"mixed":              "\"\\\n\t\x00é漢🏰'",
+		"replacement glyphs": "\uFFFD",
+	} {
+		buffer := bytes.NewBuffer([]byte{})
+		assert.NoError(t, NewRDFSink(buffer, NewDgraphSchema(ioutil.Discard, nil), NodeModeBlank).Feature(Feature{
+			Entity:    "Q1",
+			Predicate: PredicateLabel,
+			Value:     value,
+			Lang:      "en",
+		}), class)
+
+		assert.Equal(t, 1, strings.Count(buffer.String(), "\n"), class)
+		for _, c := range buffer.String()[:buffer.Len()-1] {
+			assert.False(t, c < 0x20 || c == 0x7f, "%s: raw control character %U written", class, c)
+		}
+
+		parsed, lang, err := parseLiteral(buffer.String())
+		assert.NoError(t, err, class)
+		assert.Equal(t, value, parsed, class)
+		assert.Equal(t, "en", lang, class)
+	}
+
+	// Invalid UTF-8 can not round trip, so is replaced.
+	parsed, _, err := parseLiteral(fmt.Sprintf(`_:Q1 <f_label> "%s" .`+"\n", EscapeLiteral("first\xffsecond")))
+	assert.NoError(t, err)
+	assert.Equal(t, "first\uFFFDsecond", parsed)
+}
+
+func TestPredicate(t *testing.T) {
+	p, err := newPredicate(predicateFeature, "Point in Time")
+	assert.NoError(t, err)
+	assert.Equal(t, Predicate("f_point_in_time"), p)
+
+	p, err = newPredicate(predicateEdge, " -- participant (of) -- ")
+	assert.NoError(t, err)
+	assert.Equal(t, Predicate("e__participant_of_"), p)
+
+	p, err = newPredicate(predicateFeature, "x (obsolete)")
+	assert.NoError(t, err)
+	assert.Equal(t, Predicate("f_x_obsolete_"), p)
+
+	p, err = newPredicate(predicateEdge, "P710")
+	assert.NoError(t, err)
+	assert.Equal(t, Predicate("e_P710"), p)
+
+	for _, label := range []string{"", "漢朝", " - "} {
+		_, err := newPredicate(predicateFeature, label)
+		assert.Error(t, err, label)
+	}
+}
+
+func TestDgraphSchema(t *testing.T) {
+
+	config, err := ReadSchemaConfig(strings.NewReader(`{
+		"indexes": {"string": ["hash"]},
+		"predicates": {
+			"e_participant": {"count": true},
+			"e_country": {"reverse": false},
+			"f_label": {"index": []}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	buffer := bytes.NewBuffer([]byte{})
+	s := NewDgraphSchema(buffer, config)
+	for _, p := range []PredicateSchema{
+		{Predicate: "t_battle", Type: SchemaTypeDefault},
+		{Predicate: "f_label", Type: SchemaTypeString, Lang: true},
+		{Predicate: "f_description", Type: SchemaTypeString, Lang: true},
+		{Predicate: "f_official_name", Type: SchemaTypeString},
+		{Predicate: "f_coordinate_location", Type: SchemaTypeGeo},
+		{Predicate: "e_participant", Type: SchemaTypeUID},
+		{Predicate: "e_country", Type: SchemaTypeUID},
+	} {
+		assert.NoError(t, s.Write(p))
+	}
+	s.Node(EntityNode{ID: "Q48314", Type: "t_battle"})
+	s.Field("t_battle", "e_participant")
+	s.Field("t_battle", "f_coordinate_location")
+	s.Field("", "f_official_name")
+	s.Node(EntityNode{ID: "Q102140", Type: "t_human"})
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, `e_country: [uid] .
+e_participant: [uid] @reverse @count .
+f_coordinate_location: geo @index(geo) .
+f_description: string @index(fulltext) @lang .
+f_label: string @lang .
+f_official_name: string @index(hash) .
+t_battle: default .
+
+type t_battle {
+	e_participant
+	f_coordinate_location
+	f_description
+	f_label
+}
+
+type t_human {
+	f_description
+	f_label
+}
+`, buffer.String())
+
+	_, err = ReadSchemaConfig(strings.NewReader(`{"predicates": {"label": {}}}`))
+	assert.Error(t, err)
+}
+
+func TestSchemaConflicts(t *testing.T) {
+
+	features := []Feature{
+		{Entity: "Q48314", Predicate: "f_start_time", Value: "1066", Type: SchemaTypeInt},
+		{Entity: "Q48315", Predicate: "f_start_time", Value: "early 1066", Type: SchemaTypeString},
+		{Entity: "Q48314", Predicate: "f_area", Value: "12", Type: SchemaTypeInt},
+		{Entity: "Q48315", Predicate: "f_area", Value: "2.5", Type: SchemaTypeFloat},
+	}
+
+	for mode, expected := range map[ConflictMode]struct {
+		rdf       []string
+		schema    string
+		conflicts []SchemaConflict
+	}{
+		ConflictModeWiden: {
+			rdf: []string{`_:Q48315 <f_start_time> "early 1066" .`},
+			schema: `f_area: float @index(float) .
+f_start_time: string @index(exact, term, fulltext) .
+`,
+			conflicts: []SchemaConflict{
+				{Predicate: "f_area", Types: []SchemaType{SchemaTypeFloat, SchemaTypeInt}, Resolution: "float"},
+				{Predicate: "f_start_time", Types: []SchemaType{SchemaTypeInt, SchemaTypeString}, Resolution: "string"},
+			},
+		},
+		ConflictModeSplit: {
+			rdf: []string{`_:Q48315 <f_start_time_string> "early 1066" .`, `_:Q48314 <f_area_int> "12" .`},
+			schema: `f_area: float @index(float) .
+f_area_int: int @index(int) .
+f_start_time: int @index(int) .
+f_start_time_string: string @index(exact, term, fulltext) .
+`,
+			conflicts: []SchemaConflict{
+				{Predicate: "f_area", Types: []SchemaType{SchemaTypeFloat, SchemaTypeInt}, Resolution: "f_area_int"},
+				{Predicate: "f_start_time", Types: []SchemaType{SchemaTypeInt, SchemaTypeString}, Resolution: "f_start_time_string"},
+			},
+		},
+	} {
+		// The resolution must not depend on the order the values arrive in.
+		outputs := []string{}
+		for _, reversed := range []bool{false, true} {
+			rdfBuffer := bytes.NewBuffer([]byte{})
+			schemaBuffer := bytes.NewBuffer([]byte{})
+			writer := NewWriter(NewRDFSink(rdfBuffer, NewDgraphSchema(schemaBuffer, nil), NodeModeBlank), Options{Conflicts: mode})
+			for i := range features {
+				f := features[i]
+				if reversed {
+					f = features[len(features)-1-i]
+				}
+				assert.NoError(t, writer.emitter.Feature(f, false))
+			}
+			assert.Equal(t, expected.conflicts, writer.Conflicts())
+			assert.NoError(t, writer.Close())
+			for _, line := range expected.rdf {
+				assert.Contains(t, rdfBuffer.String(), line+"\n")
+			}
+			assert.Equal(t, expected.schema, schemaBuffer.String())
+			outputs = append(outputs, rdfBuffer.String())
+		}
+		assert.Equal(t, outputs[0], outputs[1], string(mode))
+	}
+
+	// Values received before a conflict are written as the widened type as well.
+	r := &recorder{}
+	writer := NewWriter(r, Options{Conflicts: ConflictModeWiden})
+	for _, f := range features {
+		assert.NoError(t, writer.emitter.Feature(f, false))
+	}
+	assert.NoError(t, writer.Close())
+	assert.Len(t, r.features, len(features))
+	widened := map[Predicate]SchemaType{"f_start_time": SchemaTypeString, "f_area": SchemaTypeFloat}
+	for _, f := range r.features {
+		assert.Equal(t, widened[f.Predicate], f.Type, f.Value)
+	}
+
+	_, err := NewConflictMode("merge")
+	assert.Error(t, err)
+}
+
+func TestPredicateMode(t *testing.T) {
+
+	b := &endpoint.Binding{
+		"object":        {Type: "uri", Value: "http://www.wikidata.org/entity/Q48314"},
+		"objectLabel":   {Type: "literal", Value: "Battle of Hastings"},
+		"property":      {Type: "uri", Value: "http://www.wikidata.org/entity/P710"},
+		"propertyLabel": {Type: "literal", Value: "participant"},
+		"wikibaseType":  {Type: "uri", Value: "http://wikiba.se/ontology#WikibaseItem"},
+		"value":         {Type: "uri", Value: "http://www.wikidata.org/entity/Q102140"},
+		"valueLabel":    {Type: "literal", Value: "William the Conqueror"},
+	}
+
+	for mode, predicate := range map[PredicateMode]Predicate{
+		PredicateModeLabel: "e_participant",
+		PredicateModeID:    "e_P710",
+	} {
+		rdfBuffer := bytes.NewBuffer([]byte{})
+		dictionary := bytes.NewBuffer([]byte{})
+		writer := NewWriter(NewRDFSink(rdfBuffer, NewDgraphSchema(ioutil.Discard, nil), NodeModeBlank), Options{
+			Predicates: mode,
+			Dictionary: dictionary,
+		})
+		assert.NoError(t, writer.ParseBinding(b))
+		assert.NoError(t, writer.Close())
+		assert.Contains(t, rdfBuffer.String(), fmt.Sprintf("_:Q48314 <%s> _:Q102140 .\n", predicate))
+		assert.Equal(t, []PredicateDefinition{{
+			Predicate: predicate,
+			Property:  "P710",
+			Label:     "participant",
+			Datatype:  "WikibaseItem",
+			Type:      SchemaTypeUID,
+		}}, writer.Dictionary())
+		assert.Contains(t, dictionary.String(), `"predicate": "`+string(predicate)+`"`)
+	}
+
+	delete(*b, "property")
+	assert.Error(t, NewWriter(NewRDFSink(ioutil.Discard, NewDgraphSchema(ioutil.Discard, nil), NodeModeBlank), Options{Predicates: PredicateModeID}).ParseBinding(b))
+}
+
+func TestPredicateCollision(t *testing.T) {
+
+	d := newPredicateDictionary()
+	d.property(property{ID: "P580", Label: "start time", Datatype: "Time"})
+	d.property(property{ID: "P3415", Label: "start time", Datatype: "Time"})
+	d.predicate("f_start_time", "P580", SchemaTypeInt)
+	d.predicate("f_start_time", "P3415", SchemaTypeInt)
+	d.predicate("f_start_time", "P580", SchemaTypeInt)
+
+	assert.Equal(t, []PredicateDefinition{{
+		Predicate:  "f_start_time",
+		Property:   "P3415",
+		Label:      "start time",
+		Datatype:   "Time",
+		Type:       SchemaTypeInt,
+		Collisions: []string{"P580"},
+	}}, d.Definitions())
+}
+
+func TestNodeMode(t *testing.T) {
+
+	for mode, expected := range map[NodeMode]string{
+		NodeModeBlank: `_:Q48314 <e_participant> _:Q102140 .
+`,
+		NodeModeIRI: `<http://www.wikidata.org/entity/Q48314> <e_participant> <http://www.wikidata.org/entity/Q102140> .
+`,
+		NodeModeXID: `_:Q48314 <xid> "http://www.wikidata.org/entity/Q48314" .
+_:Q102140 <xid> "http://www.wikidata.org/entity/Q102140" .
+_:Q48314 <e_participant> _:Q102140 .
+`,
+	} {
+		rdfBuffer := bytes.NewBuffer([]byte{})
+		schemaBuffer := bytes.NewBuffer([]byte{})
+		s := NewRDFSink(rdfBuffer, NewDgraphSchema(schemaBuffer, nil), mode)
+		assert.NoError(t, s.EntityNode(EntityNode{ID: "Q48314"}))
+		assert.NoError(t, s.EntityNode(EntityNode{ID: "Q102140"}))
+		assert.NoError(t, s.Edge(Edge{Object: "Q48314", Predicate: "e_participant", Subject: "Q102140"}))
+		assert.NoError(t, s.Close())
+		assert.Equal(t, expected, rdfBuffer.String())
+		if mode == NodeModeXID {
+			assert.Equal(t, "xid: string @index(exact) @upsert .\n", schemaBuffer.String())
+		}
+	}
+
+	_, err := NewNodeMode("uid")
+	assert.Error(t, err)
+}
+
+func TestSortedRDFSink(t *testing.T) {
+
+	rdfBuffer := bytes.NewBuffer([]byte{})
+	// A chunk of two lines spills the lines to disk, so the result is merged from several chunks.
+	s := NewSortedRDFSink(rdfBuffer, NewDgraphSchema(ioutil.Discard, nil), NodeModeBlank, 2)
+	assert.NoError(t, s.Edge(Edge{Object: "Q48314", Predicate: "e_participant", Subject: "Q102140"}))
+	assert.NoError(t, s.Feature(Feature{Entity: "Q48314", Predicate: "f_label", Value: "Battle of Hastings", Lang: "en"}))
+	assert.NoError(t, s.EntityNode(EntityNode{ID: "Q48314", Type: "t_battle"}))
+	assert.NoError(t, s.Edge(Edge{Object: "Q48314", Predicate: "e_participant", Subject: "Q102140"}))
+	assert.NoError(t, s.Feature(Feature{Entity: "Q102140", Predicate: "f_label", Value: "William the Conqueror", Lang: "en"}))
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, `_:Q102140 <f_label> "William the Conqueror"@en .
+_:Q48314 <dgraph.type> "t_battle" .
+_:Q48314 <e_participant> _:Q102140 .
+_:Q48314 <f_label> "Battle of Hastings"@en .
+_:Q48314 <t_battle> "" .
+`, rdfBuffer.String())
+}
+
+func TestUpsertSink(t *testing.T) {
+
+	buffer := bytes.NewBuffer([]byte{})
+	s := NewUpsertSink(buffer, NewDgraphSchema(ioutil.Discard, nil), 2)
+	assert.NoError(t, s.EntityNode(EntityNode{ID: "Q48314", Type: "t_battle"}))
+	assert.NoError(t, s.Feature(Feature{Entity: "Q48314", Predicate: "f_label", Value: "Battle of Hastings", Lang: "en"}))
+	assert.NoError(t, s.Edge(Edge{Object: "Q48314", Predicate: "e_participant", Subject: "Q102140"}))
+	assert.NoError(t, s.Edge(Edge{Object: "Q48314", Predicate: "e_participant", Subject: "Q102140"}))
+	// A third node overflows the chunk, so it starts a new block.
+	assert.NoError(t, s.Edge(Edge{Object: "Q48314", Predicate: "e_location", Subject: "Q1017507"}))
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, `upsert {
+  query {
+    vQ102140 as var(func: eq(xid, "http://www.wikidata.org/entity/Q102140"))
+    vQ48314 as var(func: eq(xid, "http://www.wikidata.org/entity/Q48314"))
+  }
+  mutation {
+    set {
+      uid(vQ48314) <xid> "http://www.wikidata.org/entity/Q48314" .
+      uid(vQ48314) <dgraph.type> "t_battle" .
+      uid(vQ48314) <t_battle> "" .
+      uid(vQ48314) <f_label> "Battle of Hastings"@en .
+      uid(vQ102140) <xid> "http://www.wikidata.org/entity/Q102140" .
+      uid(vQ48314) <e_participant> uid(vQ102140) .
+    }
+  }
+}
+
+upsert {
+  query {
+    vQ1017507 as var(func: eq(xid, "http://www.wikidata.org/entity/Q1017507"))
+    vQ48314 as var(func: eq(xid, "http://www.wikidata.org/entity/Q48314"))
+  }
+  mutation {
+    set {
+      uid(vQ48314) <xid> "http://www.wikidata.org/entity/Q48314" .
+      uid(vQ1017507) <xid> "http://www.wikidata.org/entity/Q1017507" .
+      uid(vQ48314) <e_location> uid(vQ1017507) .
+    }
+  }
+}
+
+`, buffer.String())
+}
+
+func TestStats(t *testing.T) {
+
+	writer := NewWriter(NewRDFSink(bytes.NewBuffer(nil), NewDgraphSchema(bytes.NewBuffer(nil), nil), NodeModeBlank), Options{})
+	for _, b := range []*endpoint.Binding{
+		{
+			"object":                {Type: "uri", Value: "http://www.wikidata.org/entity/Q48314"},
+			"objectLabel":           {Type: "literal", Value: "Battle of Hastings"},
+			"objectInstanceOfLabel": {Type: "literal", Value: "battle"},
+			"propertyLabel":         {Type: "literal", Value: "instance of"},
+			"wikibaseType":          {Type: "uri", Value: "http://wikiba.se/ontology#WikibaseItem"},
+			"value":                 {Type: "uri", Value: "http://www.wikidata.org/entity/Q178561"},
+		},
+		{
+			"object":                {Type: "uri", Value: "http://www.wikidata.org/entity/Q48314"},
+			"objectLabel":           {Type: "literal", Value: "Battle of Hastings"},
+			"objectInstanceOfLabel": {Type: "literal", Value: "battle"},
+			"propertyLabel":         {Type: "literal", Value: "participant"},
+			"wikibaseType":          {Type: "uri", Value: "http://wikiba.se/ontology#WikibaseItem"},
+			"value":                 {Type: "uri", Value: "http://www.wikidata.org/entity/Q102140"},
+			"valueLabel":            {Type: "literal", Value: "William the Conqueror"},
+		},
+		{
+			"object":        {Type: "uri", Value: "http://www.wikidata.org/entity/Q48314"},
+			"objectLabel":   {Type: "literal", Value: "Battle of Hastings"},
+			"propertyLabel": {Type: "literal", Value: "image"},
+			"wikibaseType":  {Type: "uri", Value: "http://wikiba.se/ontology#CommonsMedia"},
+			"value":         {Type: "uri", Value: "http://commons.wikimedia.org/wiki/Special:FilePath/Bayeux.jpg"},
+		},
+	} {
+		assert.NoError(t, writer.ParseBinding(b))
+	}
+	assert.NoError(t, writer.Close())
+
+	stats := writer.Stats()
+	assert.Equal(t, 3, stats.Bindings)
+	assert.Equal(t, map[string]int{"instance of": 1, "ignored datatype": 1}, stats.Dropped)
+	assert.Equal(t, 1, stats.Predicates["e_participant"])
+	// Values repeated across the bindings of an entity are sent once.
+	assert.Equal(t, 3, stats.Predicates["f_label"])
+	assert.Equal(t, map[Predicate]int{"t_battle": 1}, stats.Types)
+}
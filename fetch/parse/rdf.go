@@ -0,0 +1,246 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package parse
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/heindl/wikivents/fetch/extsort"
+	"github.com/pkg/errors"
+)
+
+// NodeMode determines how nodes are identified in the Dgraph output formats.
+type NodeMode string
+
+const (
+	// NodeModeBlank writes nodes as blank nodes, such as _:Q123, which are new nodes on each load.
+	NodeModeBlank = NodeMode("blank")
+	// NodeModeIRI writes nodes as Wikidata IRIs, which Dgraph's loaders map to the same node across
+	// loads when given a persistent xidmap.
+	NodeModeIRI = NodeMode("iri")
+	// NodeModeXID writes blank nodes along with their IRI as an indexed xid predicate, which the
+	// upsert output and later queries match nodes on.
+	NodeModeXID = NodeMode("xid")
+)
+
+func NewNodeMode(s string) (NodeMode, error) {
+	switch m := NodeMode(s); m {
+	case NodeModeBlank, NodeModeIRI, NodeModeXID:
+		return m, nil
+	case "":
+		return NodeModeBlank, nil
+	default:
+		return "", errors.Errorf("unknown node mode [%s], expected blank, iri or xid", s)
+	}
+}
+
+// RDFSink writes events as Dgraph flavored N-Triples, and passes predicate schemas to the Dgraph schema.
+type RDFSink struct {
+	rdf    *rdf
+	schema *DgraphSchema
+}
+
+func NewRDFSink(rdfWriter io.Writer, schema *DgraphSchema, nodes NodeMode) *RDFSink {
+	return &RDFSink{
+		schema: schema,
+		rdf: &rdf{
+			m:      new(sync.Map),
+			writer: rdfWriter,
+			nodes:  nodes,
+		},
+	}
+}
+
+// NewSortedRDFSink writes the same lines as an RDFSink, but sorted on Close so that identical runs
+// write identical files. Lines are sorted on disk in chunks of the given size, so the output can be
+// larger than memory.
+func NewSortedRDFSink(rdfWriter io.Writer, schema *DgraphSchema, nodes NodeMode, chunkSize int) *RDFSink {
+	s := NewRDFSink(rdfWriter, schema, nodes)
+	s.rdf.sorter = extsort.New(chunkSize)
+	return s
+}
+
+func (Ω *RDFSink) EntityNode(n EntityNode) error {
+	if Ω.rdf.nodes == NodeModeXID {
+		Ω.schema.Field(n.Type, PredicateXID)
+		if err := Ω.rdf.WriteFeature(n.ID, PredicateXID, n.ID.IRI(), "", nil); err != nil {
+			return err
+		}
+	}
+	if n.Type == "" {
+		return nil
+	}
+	Ω.schema.Node(n)
+	if err := Ω.rdf.WriteFeature(n.ID, predicateDgraphType, string(n.Type), "", nil); err != nil {
+		return err
+	}
+	return Ω.rdf.WriteFeature(n.ID, n.Type, "", "", nil)
+}
+
+func (Ω *RDFSink) Feature(f Feature) error {
+	Ω.schema.Field(f.EntityType, f.Predicate)
+	return Ω.rdf.WriteFeature(f.Entity, f.Predicate, f.Value, f.DgraphLang(), f.Facets)
+}
+
+func (Ω *RDFSink) Edge(e Edge) error {
+	Ω.schema.Field(e.EntityType, e.Predicate)
+	return Ω.rdf.WriteEdge(e.Object, e.Predicate, e.Subject, e.Facets)
+}
+
+func (Ω *RDFSink) PredicateSchema(s PredicateSchema) error {
+	return Ω.schema.Write(s)
+}
+
+// Close writes the sorted lines, if sorted, and then the schema, as every other line of RDF is
+// written as it is received.
+// Abort removes the lines held for sorting.
+func (Ω *RDFSink) Abort() {
+	if Ω.rdf.sorter != nil {
+		Ω.rdf.sorter.Abort()
+	}
+}
+
+func (Ω *RDFSink) Close() error {
+	if err := Ω.rdf.flush(); err != nil {
+		return err
+	}
+	if Ω.rdf.nodes == NodeModeXID {
+		if err := Ω.schema.Write(PredicateSchema{Predicate: PredicateXID, Type: SchemaTypeString}); err != nil {
+			return err
+		}
+	}
+	return Ω.schema.Close()
+}
+
+// EscapeLiteral escapes a string for use within a quoted N-Triples, N-Quads or Turtle literal.
+// Control characters without a short escape are written as \uXXXX, and invalid UTF-8 is
+// replaced with the unicode replacement character.
+func EscapeLiteral(v string) string {
+	b := strings.Builder{}
+	for _, r := range v {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\b':
+			b.WriteString(`\b`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r < 0x20 || (r >= 0x7f && r <= 0x9f):
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			// Range decodes invalid UTF-8 as utf8.RuneError, which is written as U+FFFD.
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// formatFacets writes facets in Dgraph's RDF syntax, with a leading space if there are any.
+func formatFacets(facets []Facet) string {
+	if len(facets) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(facets))
+	for i, f := range facets {
+		if f.Type == SchemaTypeString {
+			pairs[i] = fmt.Sprintf(`%s="%s"`, f.Key, EscapeLiteral(f.Value))
+		} else {
+			pairs[i] = fmt.Sprintf(`%s=%s`, f.Key, f.Value)
+		}
+	}
+	return " (" + strings.Join(pairs, ", ") + ")"
+}
+
+type rdf struct {
+	m *sync.Map
+	// Lock guards the writer, since sinks are called concurrently and writers, such as a
+	// bufio.Writer, are usually not safe for concurrent use.
+	sync.Mutex
+	writer io.Writer
+	nodes  NodeMode
+	// sorter holds the lines until flush if set, and removes repeated lines instead of m.
+	sorter *extsort.Sorter
+}
+
+// write writes the line unless it has been written before, or adds it to the sorter.
+func (Ω *rdf) write(line string) error {
+	if Ω.sorter != nil {
+		return Ω.sorter.Add(strings.TrimSuffix(line, "\n"))
+	}
+	if _, ok := Ω.m.LoadOrStore(line, 1); ok {
+		return nil
+	}
+	Ω.Lock()
+	defer Ω.Unlock()
+	_, err := io.WriteString(Ω.writer, line)
+	return err
+}
+
+// flush writes the sorted lines, once each.
+func (Ω *rdf) flush() error {
+	if Ω.sorter == nil {
+		return nil
+	}
+	previous := ""
+	return Ω.sorter.Sort(func(line string) error {
+		if line == previous {
+			return nil
+		}
+		previous = line
+		_, err := io.WriteString(Ω.writer, line+"\n")
+		return errors.Wrap(err, "could not write sorted rdf")
+	})
+}
+
+func (Ω *rdf) node(id EntityID) string {
+	if Ω.nodes == NodeModeIRI {
+		return "<" + id.IRI() + ">"
+	}
+	return "_:" + string(id)
+}
+
+// formatLiteral quotes the value as a language tagged literal, or as a plain literal if the tag is empty.
+func formatLiteral(value, lang string) string {
+	literal := fmt.Sprintf(`"%s"`, EscapeLiteral(value))
+	if lang != "" {
+		literal += "@" + lang
+	}
+	return literal
+}
+
+// WriteFeature writes the value as a language tagged literal, or as a plain literal if the tag is empty.
+func (Ω *rdf) WriteFeature(entityID EntityID, predicate Predicate, value, lang string, facets []Facet) error {
+	line := fmt.Sprintf(
+		`%s <%s> %s%s .`,
+		Ω.node(entityID),
+		predicate,
+		formatLiteral(value, lang),
+		formatFacets(facets),
+	) + "\n"
+	return errors.Wrapf(Ω.write(line), "could not write [%s] [%s] [%s]", entityID, predicate, value)
+}
+
+func (Ω *rdf) WriteEdge(object EntityID, predicate Predicate, subject EntityID, facets []Facet) error {
+	line := fmt.Sprintf(
+		"%s <%s> %s%s .\n",
+		Ω.node(object),
+		predicate,
+		Ω.node(subject),
+		formatFacets(facets),
+	)
+	return errors.Wrapf(Ω.write(line), "could not write [%s, %s, %s]", object, predicate, subject)
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package parse
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ConflictMode decides how a predicate that receives values of more than one schema type is written.
+type ConflictMode string
+
+const (
+	// ConflictModeWiden writes the predicate, and each of its values, as the narrowest type holding
+	// all of its values, a float for ints and floats and otherwise a string.
+	ConflictModeWiden = ConflictMode("widen")
+	// ConflictModeSplit keeps the lowest sorted type for the predicate, and writes each other type to
+	// the predicate suffixed by that type, like f_start_time_string.
+	ConflictModeSplit = ConflictMode("split")
+)
+
+func NewConflictMode(s string) (ConflictMode, error) {
+	switch m := ConflictMode(s); m {
+	case ConflictModeWiden, ConflictModeSplit:
+		return m, nil
+	case "":
+		return ConflictModeWiden, nil
+	default:
+		return "", errors.Errorf("invalid schema conflict mode [%s], expected widen or split", s)
+	}
+}
+
+// SchemaConflict records a predicate that received more than one schema type, and how it was resolved.
+type SchemaConflict struct {
+	Predicate Predicate
+	Types     []SchemaType
+	// Resolution is the widened type, or the suffixed predicates in split mode.
+	Resolution string
+}
+
+// schemaRegistry tracks the types received by each predicate, and resolves conflicting types by a
+// fixed rule, so that the result does not depend on the order values arrive in.
+type schemaRegistry struct {
+	sync.Mutex
+	mode ConflictMode
+	// types holds the types received by each predicate, and whether any value of each was language tagged.
+	types map[Predicate]map[SchemaType]bool
+}
+
+func newSchemaRegistry(mode ConflictMode) *schemaRegistry {
+	if mode == "" {
+		mode = ConflictModeWiden
+	}
+	return &schemaRegistry{
+		mode:  mode,
+		types: map[Predicate]map[SchemaType]bool{},
+	}
+}
+
+func widen(a, b SchemaType) SchemaType {
+	if a == b {
+		return a
+	}
+	numeric := map[SchemaType]bool{SchemaTypeInt: true, SchemaTypeFloat: true}
+	if numeric[a] && numeric[b] {
+		return SchemaTypeFloat
+	}
+	return SchemaTypeString
+}
+
+// typeSuffix is the predicate suffix of a type in split mode.
+func typeSuffix(t SchemaType) string {
+	if t == SchemaTypeStringList {
+		return "string_list"
+	}
+	return string(t)
+}
+
+// register records the type of a value of the predicate.
+func (Ω *schemaRegistry) register(s PredicateSchema) {
+	Ω.Lock()
+	defer Ω.Unlock()
+	if _, ok := Ω.types[s.Predicate]; !ok {
+		Ω.types[s.Predicate] = map[SchemaType]bool{}
+	}
+	// A predicate holds language tagged values if any value is tagged, which Dgraph accepts alongside untagged ones.
+	Ω.types[s.Predicate][s.Type] = Ω.types[s.Predicate][s.Type] || s.Lang
+}
+
+// sorted returns the types of the predicate, sorted. The caller must hold the lock.
+func (Ω *schemaRegistry) sorted(p Predicate) []SchemaType {
+	res := make([]SchemaType, 0, len(Ω.types[p]))
+	for t := range Ω.types[p] {
+		res = append(res, t)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// widest returns the type that holds every type of the predicate. The caller must hold the lock.
+func (Ω *schemaRegistry) widest(p Predicate) SchemaType {
+	types := Ω.sorted(p)
+	res := types[0]
+	for _, t := range types[1:] {
+		res = widen(res, t)
+	}
+	return res
+}
+
+// split is the predicate of values of the type in split mode.
+func split(p Predicate, t SchemaType, types []SchemaType) Predicate {
+	if t == types[0] {
+		return p
+	}
+	return Predicate(string(p) + "_" + typeSuffix(t))
+}
+
+// resolve returns the predicate and type a value of the registered schema is written with, by the
+// types registered so far, which is final once every value is registered.
+func (Ω *schemaRegistry) resolve(s PredicateSchema) (Predicate, SchemaType) {
+	Ω.Lock()
+	defer Ω.Unlock()
+	if Ω.mode == ConflictModeWiden {
+		return s.Predicate, Ω.widest(s.Predicate)
+	}
+	return split(s.Predicate, s.Type, Ω.sorted(s.Predicate)), s.Type
+}
+
+// Schemas returns the resolved schema of each predicate, sorted by predicate.
+func (Ω *schemaRegistry) Schemas() []PredicateSchema {
+	Ω.Lock()
+	defer Ω.Unlock()
+	res := []PredicateSchema{}
+	for p, types := range Ω.types {
+		if Ω.mode == ConflictModeWiden {
+			s := PredicateSchema{Predicate: p, Type: Ω.widest(p)}
+			for _, lang := range types {
+				s.Lang = s.Lang || lang
+			}
+			res = append(res, s)
+			continue
+		}
+		sorted := Ω.sorted(p)
+		for _, t := range sorted {
+			res = append(res, PredicateSchema{Predicate: split(p, t, sorted), Type: t, Lang: types[t]})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Predicate < res[j].Predicate
+	})
+	return res
+}
+
+// Conflicts returns the predicates that received more than one type, sorted by predicate.
+func (Ω *schemaRegistry) Conflicts() []SchemaConflict {
+	Ω.Lock()
+	defer Ω.Unlock()
+	res := []SchemaConflict{}
+	for p := range Ω.types {
+		types := Ω.sorted(p)
+		if len(types) < 2 {
+			continue
+		}
+		c := SchemaConflict{Predicate: p, Types: types}
+		if Ω.mode == ConflictModeWiden {
+			c.Resolution = string(Ω.widest(p))
+		} else {
+			predicates := []string{}
+			for _, t := range types[1:] {
+				predicates = append(predicates, string(split(p, t, types)))
+			}
+			c.Resolution = strings.Join(predicates, ", ")
+		}
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Predicate < res[j].Predicate
+	})
+	return res
+}
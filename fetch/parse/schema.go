@@ -0,0 +1,236 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SchemaConfig overrides the directives written for each predicate in the Dgraph schema.
+//
+//	{
+//	  "indexes": {"string": ["hash"]},
+//	  "predicates": {
+//	    "e_participant": {"reverse": true, "count": true},
+//	    "f_description": {"index": []},
+//	    "f_label": {"index": ["exact"], "upsert": true}
+//	  }
+//	}
+type SchemaConfig struct {
+	// Indexes replace the default index tokenizers of a schema type.
+	Indexes    map[SchemaType][]string           `json:"indexes"`
+	Predicates map[Predicate]PredicateDirectives `json:"predicates"`
+}
+
+// PredicateDirectives are the schema directives of one predicate. Unset fields fall back to the
+// defaults of the predicate's type, and an empty index list removes the index.
+type PredicateDirectives struct {
+	Index   []string `json:"index"`
+	Reverse *bool    `json:"reverse"`
+	Count   *bool    `json:"count"`
+	Upsert  *bool    `json:"upsert"`
+}
+
+func ReadSchemaConfig(r io.Reader) (*SchemaConfig, error) {
+	c := SchemaConfig{}
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, errors.Wrap(err, "could not decode schema config")
+	}
+	for p := range c.Predicates {
+		if !validPredicate.MatchString(string(p)) {
+			return nil, errors.Errorf("invalid predicate [%s] in schema config", p)
+		}
+	}
+	return &c, nil
+}
+
+// PredicateXID holds the Wikidata IRI of each node in the xid node mode, so that loads can upsert on it.
+const PredicateXID = Predicate("xid")
+
+// predicateDgraphType sets the node types Dgraph uses to expand the predicates of a node.
+const predicateDgraphType = Predicate("dgraph.type")
+
+// defaultIndexes are the index tokenizers of each schema type, unless the config overrides them.
+var defaultIndexes = map[SchemaType][]string{
+	SchemaTypeString:     {"exact", "term", "fulltext"},
+	SchemaTypeStringList: {"exact", "term"},
+	SchemaTypeInt:        {"int"},
+	SchemaTypeFloat:      {"float"},
+	SchemaTypeBool:       {"bool"},
+	SchemaTypeDateTime:   {"year"},
+	SchemaTypeGeo:        {"geo"},
+}
+
+// defaultDirectives override the defaults of the schema type for predicates that need them.
+var defaultDirectives = map[Predicate]PredicateDirectives{
+	PredicateDescription: {Index: []string{"fulltext"}},
+	PredicateXID:         {Index: []string{"exact"}, Upsert: &upsert},
+}
+
+var upsert = true
+
+// DgraphSchema collects predicate schemas and the predicates used by each t_ class, and writes
+// them in Dgraph's schema syntax on Close, followed by a type definition for each class. Type
+// definitions list their fields without types, which needs Dgraph 20.03 or later.
+type DgraphSchema struct {
+	sync.Mutex
+	writer     io.Writer
+	config     SchemaConfig
+	lines      map[string]struct{}
+	predicates map[Predicate]struct{}
+	// fields holds the predicates of each type, as values arrive with the type of their entity.
+	fields map[Predicate]map[Predicate]struct{}
+}
+
+// NewDgraphSchema creates a schema writer. The config may be nil.
+func NewDgraphSchema(writer io.Writer, config *SchemaConfig) *DgraphSchema {
+	s := &DgraphSchema{
+		writer:     writer,
+		lines:      map[string]struct{}{},
+		predicates: map[Predicate]struct{}{},
+		fields:     map[Predicate]map[Predicate]struct{}{},
+	}
+	if config != nil {
+		s.config = *config
+	}
+	return s
+}
+
+func (Ω *DgraphSchema) directives(s PredicateSchema) []string {
+	res := []string{}
+	c := Ω.config.Predicates[s.Predicate]
+
+	index, ok := Ω.config.Indexes[s.Type]
+	if !ok {
+		index = defaultIndexes[s.Type]
+	}
+	if d := defaultDirectives[s.Predicate].Index; d != nil {
+		index = d
+	}
+	if c.Index != nil {
+		index = c.Index
+	}
+	if len(index) > 0 {
+		res = append(res, fmt.Sprintf("@index(%s)", strings.Join(index, ", ")))
+	}
+
+	// Every edge has been reversible since the first version of the schema.
+	reverse := s.Type == SchemaTypeUID
+	if c.Reverse != nil {
+		reverse = *c.Reverse && s.Type == SchemaTypeUID
+	}
+	if reverse {
+		res = append(res, "@reverse")
+	}
+	if c.Count != nil && *c.Count {
+		res = append(res, "@count")
+	}
+	upsert := defaultDirectives[s.Predicate].Upsert
+	if c.Upsert != nil {
+		upsert = c.Upsert
+	}
+	if upsert != nil && *upsert && len(index) > 0 {
+		// Dgraph requires an index on upsert predicates.
+		res = append(res, "@upsert")
+	}
+	if s.Lang {
+		res = append(res, "@lang")
+	}
+	return res
+}
+
+func (Ω *DgraphSchema) Write(s PredicateSchema) error {
+	t := string(s.Type)
+	if s.Type == SchemaTypeUID {
+		// Since Dgraph 1.1 a plain uid predicate holds a single edge, so edges are lists.
+		t = "[uid]"
+	}
+	line := strings.Join(append([]string{string(s.Predicate) + ":", t}, Ω.directives(s)...), " ") + " ."
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.lines[line] = struct{}{}
+	Ω.predicates[s.Predicate] = struct{}{}
+	return nil
+}
+
+// typeFields returns the fields of the type, creating them if needed. The caller must hold the lock.
+func (Ω *DgraphSchema) typeFields(t Predicate) map[Predicate]struct{} {
+	if _, ok := Ω.fields[t]; !ok {
+		Ω.fields[t] = map[Predicate]struct{}{}
+	}
+	return Ω.fields[t]
+}
+
+// Node records the type of the node, so that it has a type definition.
+func (Ω *DgraphSchema) Node(n EntityNode) {
+	if n.Type == "" {
+		return
+	}
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.typeFields(n.Type)
+}
+
+// Field records a predicate of a value of an entity of the type. Values are sent along with the
+// type of their entity, which the entity query repeats for every value, so nothing is kept for
+// each entity. Values without a type, such as terms, are not recorded.
+func (Ω *DgraphSchema) Field(t, p Predicate) {
+	if t == "" {
+		return
+	}
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.typeFields(t)[p] = struct{}{}
+}
+
+// termFields are given to every type, since terms are requested without the type of their entity.
+var termFields = []Predicate{PredicateLabel, PredicateDescription, PredicateAlias}
+
+// Close writes the predicate schemas, sorted, followed by the type definitions.
+func (Ω *DgraphSchema) Close() error {
+	Ω.Lock()
+	defer Ω.Unlock()
+
+	lines := make([]string, 0, len(Ω.lines))
+	for l := range Ω.lines {
+		lines = append(lines, l)
+	}
+	sort.Strings(lines)
+
+	types := make([]string, 0, len(Ω.fields))
+	for t := range Ω.fields {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fields := Ω.fields[Predicate(t)]
+		for _, p := range termFields {
+			if _, ok := Ω.predicates[p]; ok {
+				fields[p] = struct{}{}
+			}
+		}
+		predicates := make([]string, 0, len(fields))
+		for p := range fields {
+			predicates = append(predicates, "\t"+string(p)+"\n")
+		}
+		sort.Strings(predicates)
+		lines = append(lines, fmt.Sprintf("\ntype %s {\n%s}", t, strings.Join(predicates, "")))
+	}
+
+	for _, l := range lines {
+		if _, err := io.WriteString(Ω.writer, l+"\n"); err != nil {
+			return errors.Wrap(err, "could not write schema")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/heindl/wikivents/fetch/extsort"
+	"github.com/pkg/errors"
+)
+
+// Sink receives the typed events produced by the Writer. Batches are parsed concurrently,
+// so implementations must be safe for concurrent use, and should expect repeated events
+// because the same entity is often a value of several others.
+type Sink interface {
+	EntityNode(EntityNode) error
+	Feature(Feature) error
+	Edge(Edge) error
+	PredicateSchema(PredicateSchema) error
+	// Close is called once after the last event, but does not close the underlying writers.
+	Close() error
+}
+
+// Aborter is implemented by sinks that hold temporary files until Close. Abort removes them instead
+// when the run fails before Close.
+type Aborter interface {
+	Abort()
+}
+
+// EntityNode declares a node, along with a type if one is known.
+type EntityNode struct {
+	ID   EntityID
+	Type Predicate
+}
+
+// Feature is a literal value of a node.
+type Feature struct {
+	Entity    EntityID
+	Predicate Predicate
+	// Property is the ID of the Wikidata property the value was read from, if any.
+	Property string
+	// EntityType is the type of the entity in the binding the value was read from, if it had one.
+	EntityType Predicate
+	Value      string
+	// Lang is the language tag of the value, if any.
+	Lang   string
+	Type   SchemaType
+	Facets []Facet
+}
+
+// DgraphLang is the language tag of the value in the Dgraph formats. Dgraph does not support language
+// tags on list predicates, so list values are written untagged.
+func (Ω Feature) DgraphLang() string {
+	if Ω.Type == SchemaTypeStringList {
+		return ""
+	}
+	return Ω.Lang
+}
+
+// Edge connects the object node to the subject node.
+type Edge struct {
+	Object    EntityID
+	Predicate Predicate
+	// Property is the ID of the Wikidata property the edge was read from, if any.
+	Property string
+	// EntityType is the type of the object in the binding the edge was read from, if it had one.
+	EntityType Predicate
+	Subject    EntityID
+	Facets     []Facet
+}
+
+// PredicateSchema is sent once for each predicate when the Writer is closed, with any conflict
+// between the types of its values resolved.
+type PredicateSchema struct {
+	Predicate Predicate
+	Type      SchemaType
+	// Lang is true if the predicate holds language tagged values.
+	Lang bool
+}
+
+// Facet is a key value pair attached to a feature or edge.
+type Facet struct {
+	Key   string
+	Value string
+	Type  SchemaType
+}
+
+// emitter passes model events on to the sink, after resolving the schema type of each predicate.
+type emitter struct {
+	sink       Sink
+	registry   *schemaRegistry
+	dictionary *predicateDictionary
+	stats      *stats
+	// references holds the lowest reference ID of each value, when references are written as facets.
+	references *referenceIndex
+	// deferred holds the events on disk until Close, since the type of each value is only resolved
+	// once every value has been received.
+	deferred *extsort.Sorter
+}
+
+// deferredEvent is an event held on disk until Close.
+type deferredEvent struct {
+	Node    *EntityNode `json:",omitempty"`
+	Feature *Feature    `json:",omitempty"`
+	Edge    *Edge       `json:",omitempty"`
+	// Reference is set for the features and edges of values written with references as facets.
+	Reference *valueReference `json:",omitempty"`
+}
+
+// valueReference is the value a feature or edge writes, and the reference it is written with,
+// which is empty if the value has none.
+type valueReference struct {
+	Value     string
+	Reference EntityID
+}
+
+// referenceIndex holds the lowest reference ID of each value.
+type referenceIndex struct {
+	sync.Mutex
+	lowest map[string]EntityID
+}
+
+func newReferenceIndex() *referenceIndex {
+	return &referenceIndex{lowest: map[string]EntityID{}}
+}
+
+func (Ω *referenceIndex) add(r valueReference) {
+	Ω.Lock()
+	defer Ω.Unlock()
+	if lowest, ok := Ω.lowest[r.Value]; !ok || r.Reference < lowest {
+		Ω.lowest[r.Value] = r.Reference
+	}
+}
+
+// isLowest is true if the value has no reference with a lower ID, which is final once every value
+// has been received.
+func (Ω *referenceIndex) isLowest(r valueReference) bool {
+	Ω.Lock()
+	defer Ω.Unlock()
+	return Ω.lowest[r.Value] == r.Reference
+}
+
+// hold holds the event on disk, keyed by its entity so that the events of each entity are sent
+// together, with its node first.
+func (Ω *emitter) hold(entity EntityID, order int, e deferredEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "could not encode event")
+	}
+	return Ω.deferred.Add(fmt.Sprintf("%s\t%d\t%s", entity, order, b))
+}
+
+// property records the label and datatype of a property for the predicate dictionary.
+func (Ω *emitter) property(p property) {
+	Ω.dictionary.property(p)
+}
+
+func (Ω *emitter) EntityNode(n EntityNode) error {
+	if n.Type != "" {
+		Ω.registry.register(PredicateSchema{Predicate: n.Type, Type: SchemaTypeDefault})
+		Ω.stats.entity(n.Type, n.ID)
+	}
+	return Ω.hold(n.ID, 0, deferredEvent{Node: &n})
+}
+
+// Feature sends the feature, and marks its predicate as language tagged if lang is true.
+func (Ω *emitter) Feature(f Feature, lang bool) error {
+	return Ω.referencedFeature(f, lang, nil)
+}
+
+// referencedFeature sends the feature, unless a reference with a lower ID is received for its value.
+func (Ω *emitter) referencedFeature(f Feature, lang bool, r *valueReference) error {
+	Ω.registry.register(PredicateSchema{Predicate: f.Predicate, Type: f.Type, Lang: lang})
+	if r != nil {
+		Ω.references.add(*r)
+	}
+	return Ω.hold(f.Entity, 1, deferredEvent{Feature: &f, Reference: r})
+}
+
+func (Ω *emitter) sendFeature(f Feature) error {
+	f.Predicate, f.Type = Ω.registry.resolve(PredicateSchema{Predicate: f.Predicate, Type: f.Type})
+	Ω.dictionary.predicate(f.Predicate, f.Property, f.Type)
+	Ω.stats.predicate(f.Predicate)
+	return Ω.sink.Feature(f)
+}
+
+func (Ω *emitter) Edge(e Edge) error {
+	return Ω.referencedEdge(e, nil)
+}
+
+// referencedEdge sends the edge, unless a reference with a lower ID is received for its value.
+func (Ω *emitter) referencedEdge(e Edge, r *valueReference) error {
+	Ω.registry.register(PredicateSchema{Predicate: e.Predicate, Type: SchemaTypeUID})
+	if r != nil {
+		Ω.references.add(*r)
+	}
+	return Ω.hold(e.Object, 2, deferredEvent{Edge: &e, Reference: r})
+}
+
+func (Ω *emitter) sendEdge(e Edge) error {
+	Ω.dictionary.predicate(e.Predicate, e.Property, SchemaTypeUID)
+	Ω.stats.predicate(e.Predicate)
+	return Ω.sink.Edge(e)
+}
+
+// send sends the deferred events in order, skipping the repeated ones.
+func (Ω *emitter) send() error {
+	previous := ""
+	return Ω.deferred.Sort(func(line string) error {
+		if line == previous {
+			return nil
+		}
+		previous = line
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			return errors.Errorf("invalid deferred event [%s]", line)
+		}
+		e := deferredEvent{}
+		if err := json.Unmarshal([]byte(fields[2]), &e); err != nil {
+			return errors.Wrap(err, "could not decode event")
+		}
+		if e.Reference != nil && !Ω.references.isLowest(*e.Reference) {
+			// Dgraph keeps one set of facets for each triple, so a value is written once, with the
+			// reference of the lowest ID, rather than once for each. Write references as nodes to
+			// keep them all.
+			Ω.stats.drop("additional reference")
+			return nil
+		}
+		switch {
+		case e.Node != nil:
+			return Ω.sink.EntityNode(*e.Node)
+		case e.Feature != nil:
+			return Ω.sendFeature(*e.Feature)
+		case e.Edge != nil:
+			return Ω.sendEdge(*e.Edge)
+		}
+		return nil
+	})
+}
+
+// Abort removes the values held on disk, along with those of the sink.
+func (Ω *emitter) Abort() {
+	Ω.deferred.Abort()
+	if a, ok := Ω.sink.(Aborter); ok {
+		a.Abort()
+	}
+}
+
+// Close sends the deferred events and the resolved schema of each predicate, and closes the sink.
+func (Ω *emitter) Close() error {
+	if err := Ω.send(); err != nil {
+		return err
+	}
+	for _, s := range Ω.registry.Schemas() {
+		if err := Ω.sink.PredicateSchema(s); err != nil {
+			return err
+		}
+	}
+	return Ω.sink.Close()
+}
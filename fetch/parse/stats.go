@@ -0,0 +1,87 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package parse
+
+import "sync"
+
+// Stats counts the bindings a Writer received and what it wrote from them.
+type Stats struct {
+	Bindings int `json:"bindings"`
+	// Dropped counts the bindings that were ignored, by reason.
+	Dropped map[string]int `json:"dropped"`
+	// Predicates counts the values written for each feature and edge predicate.
+	Predicates map[Predicate]int `json:"predicates"`
+	// Types counts the entities of each type.
+	Types map[Predicate]int `json:"types"`
+}
+
+type stats struct {
+	sync.Mutex
+	bindings   int
+	dropped    map[string]int
+	predicates map[Predicate]int
+	types      map[Predicate]map[EntityID]struct{}
+}
+
+func newStats() *stats {
+	return &stats{
+		dropped:    map[string]int{},
+		predicates: map[Predicate]int{},
+		types:      map[Predicate]map[EntityID]struct{}{},
+	}
+}
+
+func (Ω *stats) binding() {
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.bindings++
+}
+
+func (Ω *stats) drop(reason string) {
+	Ω.Lock()
+	defer Ω.Unlock()
+	if reason == "" {
+		reason = "unknown"
+	}
+	Ω.dropped[reason]++
+}
+
+func (Ω *stats) predicate(p Predicate) {
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.predicates[p]++
+}
+
+// entity counts the entity once for its type, however many times it is written.
+func (Ω *stats) entity(t Predicate, id EntityID) {
+	Ω.Lock()
+	defer Ω.Unlock()
+	if _, ok := Ω.types[t]; !ok {
+		Ω.types[t] = map[EntityID]struct{}{}
+	}
+	Ω.types[t][id] = struct{}{}
+}
+
+func (Ω *stats) Stats() Stats {
+	Ω.Lock()
+	defer Ω.Unlock()
+	res := Stats{
+		Bindings:   Ω.bindings,
+		Dropped:    map[string]int{},
+		Predicates: map[Predicate]int{},
+		Types:      map[Predicate]int{},
+	}
+	for reason, n := range Ω.dropped {
+		res.Dropped[reason] = n
+	}
+	for p, n := range Ω.predicates {
+		res.Predicates[p] = n
+	}
+	for t, entities := range Ω.types {
+		res.Types[t] = len(entities)
+	}
+	return res
+}
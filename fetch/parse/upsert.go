@@ -0,0 +1,158 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package parse
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// UpsertSink writes events as Dgraph upsert blocks. Each block finds the nodes of a chunk by their
+// xid and sets their predicates, creating the nodes that do not exist yet, so that repeated and
+// overlapping loads merge into one graph. Blocks are separated by a blank line, and each can be
+// sent to Dgraph's /mutate endpoint as it is.
+type UpsertSink struct {
+	sync.Mutex
+	writer    io.Writer
+	schema    *DgraphSchema
+	chunkSize int
+	nodes     map[EntityID]struct{}
+	lines     []string
+	written   map[string]struct{}
+}
+
+func NewUpsertSink(writer io.Writer, schema *DgraphSchema, chunkSize int) *UpsertSink {
+	return &UpsertSink{
+		writer:    writer,
+		schema:    schema,
+		chunkSize: chunkSize,
+		nodes:     map[EntityID]struct{}{},
+		written:   map[string]struct{}{},
+	}
+}
+
+// upsertVar is the query variable of the node. Reference hashes can start with a digit, so every
+// variable is prefixed, and the dashes of statement IDs, which variables can not hold, are replaced.
+func upsertVar(id EntityID) string {
+	return "v" + strings.Replace(string(id), "-", "_", -1)
+}
+
+// add buffers the line, which may be empty to only declare the nodes, writing the block first if the nodes of the line would overflow the chunk.
+func (Ω *UpsertSink) add(line string, nodes ...EntityID) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	added := 0
+	for _, id := range nodes {
+		if _, ok := Ω.nodes[id]; !ok {
+			added++
+		}
+	}
+	if len(Ω.nodes) > 0 && len(Ω.nodes)+added > Ω.chunkSize {
+		if err := Ω.flush(); err != nil {
+			return err
+		}
+	}
+	for _, id := range nodes {
+		if _, ok := Ω.nodes[id]; ok {
+			continue
+		}
+		Ω.nodes[id] = struct{}{}
+		Ω.lines = append(Ω.lines, fmt.Sprintf("uid(%s) <%s> %s .", upsertVar(id), PredicateXID, formatLiteral(id.IRI(), "")))
+	}
+	if _, ok := Ω.written[line]; !ok && line != "" {
+		Ω.written[line] = struct{}{}
+		Ω.lines = append(Ω.lines, line)
+	}
+	return nil
+}
+
+// flush writes the buffered block. The caller must hold the lock.
+func (Ω *UpsertSink) flush() error {
+	if len(Ω.nodes) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(Ω.nodes))
+	for id := range Ω.nodes {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+
+	b := strings.Builder{}
+	b.WriteString("upsert {\n  query {\n")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "    %s as var(func: eq(%s, %s))\n", upsertVar(EntityID(id)), PredicateXID, formatLiteral(EntityID(id).IRI(), ""))
+	}
+	b.WriteString("  }\n  mutation {\n    set {\n")
+	for _, l := range Ω.lines {
+		b.WriteString("      " + l + "\n")
+	}
+	b.WriteString("    }\n  }\n}\n\n")
+
+	if _, err := io.WriteString(Ω.writer, b.String()); err != nil {
+		return errors.Wrap(err, "could not write upsert block")
+	}
+	Ω.nodes = map[EntityID]struct{}{}
+	Ω.written = map[string]struct{}{}
+	Ω.lines = nil
+	return nil
+}
+
+func (Ω *UpsertSink) EntityNode(n EntityNode) error {
+	Ω.schema.Field(n.Type, PredicateXID)
+	if n.Type == "" {
+		return Ω.add("", n.ID)
+	}
+	Ω.schema.Node(n)
+	if err := Ω.add(fmt.Sprintf("uid(%s) <%s> %s .", upsertVar(n.ID), predicateDgraphType, formatLiteral(string(n.Type), "")), n.ID); err != nil {
+		return err
+	}
+	return Ω.add(fmt.Sprintf(`uid(%s) <%s> "" .`, upsertVar(n.ID), n.Type), n.ID)
+}
+
+func (Ω *UpsertSink) Feature(f Feature) error {
+	Ω.schema.Field(f.EntityType, f.Predicate)
+	return Ω.add(fmt.Sprintf(
+		"uid(%s) <%s> %s%s .",
+		upsertVar(f.Entity),
+		f.Predicate,
+		formatLiteral(f.Value, f.DgraphLang()),
+		formatFacets(f.Facets),
+	), f.Entity)
+}
+
+func (Ω *UpsertSink) Edge(e Edge) error {
+	Ω.schema.Field(e.EntityType, e.Predicate)
+	return Ω.add(fmt.Sprintf(
+		"uid(%s) <%s> uid(%s)%s .",
+		upsertVar(e.Object),
+		e.Predicate,
+		upsertVar(e.Subject),
+		formatFacets(e.Facets),
+	), e.Object, e.Subject)
+}
+
+func (Ω *UpsertSink) PredicateSchema(s PredicateSchema) error {
+	return Ω.schema.Write(s)
+}
+
+// Close writes the remaining block and the schema.
+func (Ω *UpsertSink) Close() error {
+	Ω.Lock()
+	err := Ω.flush()
+	Ω.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := Ω.schema.Write(PredicateSchema{Predicate: PredicateXID, Type: SchemaTypeString}); err != nil {
+		return err
+	}
+	return Ω.schema.Close()
+}
@@ -7,43 +7,113 @@ package parse
 
 import (
 	"io"
-	"sync"
 
 	"github.com/heindl/wikivents/fetch/endpoint"
+	"github.com/heindl/wikivents/fetch/extsort"
+	"github.com/sirupsen/logrus"
 )
 
 type Writer struct {
-	schema *schema
-	rdf    *rdf
+	emitter *emitter
+	opts    Options
 }
 
-func NewWriter(rdfWriter, schemaWriter io.Writer) *Writer {
-	return &Writer{
-		schema: &schema{
-			m:      new(sync.Map),
-			writer: schemaWriter,
-		},
-		rdf: &rdf{
-			m:      new(sync.Map),
-			writer: rdfWriter,
+// Options configure how parsed bindings are written.
+type Options struct {
+	References ReferenceMode
+	Conflicts  ConflictMode
+	Predicates PredicateMode
+	// Dictionary receives the predicate dictionary as JSON when the Writer is closed, if set.
+	Dictionary io.Writer
+	// Dates adds the full date and precision of each time value as facets, for outputs that order
+	// events within a year.
+	Dates bool
+}
+
+func NewWriter(sink Sink, opts Options) *Writer {
+	w := &Writer{
+		opts: opts,
+		emitter: &emitter{
+			sink:       sink,
+			registry:   newSchemaRegistry(opts.Conflicts),
+			dictionary: newPredicateDictionary(),
+			stats:      newStats(),
+			references: newReferenceIndex(),
+			// The type of each value is only final once every value has been received.
+			deferred: extsort.New(extsort.DefaultChunkSize),
 		},
 	}
+	return w
 }
 
 func (w *Writer) ParseBinding(b *endpoint.Binding) error {
 
-	p := parser{b}
+	w.emitter.stats.binding()
+	p := parser{binding: b, predicates: w.opts.Predicates, dates: w.opts.Dates}
+	if p.IsTerm() {
+		t, err := p.Term()
+		if err != nil || t == nil {
+			w.dropped(&p, err)
+			return err
+		}
+		return t.Write(w.emitter)
+	}
+
 	object, err := p.Entity("object")
 	if err != nil || object == nil {
+		w.dropped(&p, err)
 		return err
 	}
-	if err := object.Write(w.rdf, w.schema); err != nil {
+	if err := object.Write(w.emitter); err != nil {
 		return err
 	}
 	value, err := p.Value()
 	if err != nil || value == nil {
+		w.dropped(&p, err)
+		return err
+	}
+	return value.Write(object, w.opts.References, w.emitter)
+
+}
+
+// dropped counts a binding that was ignored rather than failed.
+func (w *Writer) dropped(p *parser, err error) {
+	if err == nil {
+		w.emitter.stats.drop(p.dropped)
+	}
+}
+
+// Close reports any schema conflicts, and sends the predicate schemas to the sink before closing it.
+func (w *Writer) Close() error {
+	for _, c := range w.Conflicts() {
+		logrus.Warnf("Predicate [%s] has values of types %v, resolved as [%s]", c.Predicate, c.Types, c.Resolution)
+	}
+	// Predicates are recorded in the dictionary as the emitter sends their values on Close.
+	if err := w.emitter.Close(); err != nil {
 		return err
 	}
-	return value.Write(object, w.rdf, w.schema)
+	if w.opts.Dictionary != nil {
+		return w.emitter.dictionary.Write(w.opts.Dictionary)
+	}
+	return nil
+}
+
+// Abort removes the temporary files of a run that failed before Close, instead of closing the sink.
+func (w *Writer) Abort() {
+	w.emitter.Abort()
+}
+
+// Dictionary returns the property each predicate was written from.
+func (w *Writer) Dictionary() []PredicateDefinition {
+	return w.emitter.dictionary.Definitions()
+}
+
+// Conflicts returns the predicates that received values of more than one schema type.
+func (w *Writer) Conflicts() []SchemaConflict {
+	return w.emitter.registry.Conflicts()
+}
 
+// Stats returns the counts of bindings received and dropped, and of values and entities written.
+func (w *Writer) Stats() Stats {
+	return w.emitter.stats.Stats()
 }
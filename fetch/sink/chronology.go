@@ -0,0 +1,257 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/heindl/wikivents/fetch/extsort"
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// Chronology writes one JSON event per line for every entity with a start date, ordered by that
+// date. Events arrive in no particular order, so the records each needs are sorted by entity on
+// disk, assembled into events, and sorted again by date, without holding the graph in memory.
+type Chronology struct {
+	writer    io.Writer
+	chunkSize int
+	entities  *extsort.Sorter
+}
+
+func NewChronology(writer io.Writer, chunkSize int) *Chronology {
+	return &Chronology{writer: writer, chunkSize: chunkSize, entities: extsort.New(chunkSize)}
+}
+
+// chronologyRecord is one of the values of an entity that its event is assembled from.
+type chronologyRecord struct {
+	Kind      string `json:"k"`
+	Value     string `json:"v"`
+	Lang      string `json:"l,omitempty"`
+	Property  string `json:"p,omitempty"`
+	Rank      int    `json:"r,omitempty"`
+	Date      string `json:"d,omitempty"`
+	Precision *int   `json:"n,omitempty"`
+}
+
+// ChronologyEvent is an entity at its start date. Date is the Wikidata time value, or the year
+// when that is not known, and Precision is the Wikidata precision of the date, such as 9 for a year
+// and 11 for a day.
+type ChronologyEvent struct {
+	ID          parse.EntityID `json:"id"`
+	Label       string         `json:"label"`
+	Description string         `json:"description,omitempty"`
+	Types       []string       `json:"types"`
+	Date        string         `json:"date"`
+	Year        int            `json:"year"`
+	Precision   *int           `json:"precision,omitempty"`
+	Property    string         `json:"property,omitempty"`
+}
+
+func (Ω *Chronology) add(id parse.EntityID, r chronologyRecord) error {
+	if id.IsReference() {
+		return nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrapf(err, "could not encode record of %s", id)
+	}
+	// A tab sorts before the characters of any ID, which keeps the records of an entity together.
+	return Ω.entities.Add(string(id) + "\t" + string(b))
+}
+
+func (Ω *Chronology) EntityNode(n parse.EntityNode) error {
+	if n.Type == "" {
+		return nil
+	}
+	return Ω.add(n.ID, chronologyRecord{Kind: "type", Value: strings.TrimPrefix(string(n.Type), "t_")})
+}
+
+func (Ω *Chronology) Feature(f parse.Feature) error {
+	switch f.Predicate {
+	case parse.PredicateLabel:
+		return Ω.add(f.Entity, chronologyRecord{Kind: "label", Value: f.Value, Lang: f.Lang})
+	case parse.PredicateDescription:
+		return Ω.add(f.Entity, chronologyRecord{Kind: "description", Value: f.Value, Lang: f.Lang})
+	}
+	rank := matches(f.Property, f.Predicate, startProperties, startPredicates)
+	if rank < 0 || f.Type != parse.SchemaTypeInt {
+		return nil
+	}
+	r := chronologyRecord{Kind: "date", Value: f.Value, Property: f.Property, Rank: rank}
+	for _, facet := range f.Facets {
+		switch facet.Key {
+		case "date":
+			r.Date = facet.Value
+		case "precision":
+			if p, err := strconv.Atoi(facet.Value); err == nil {
+				r.Precision = &p
+			}
+		}
+	}
+	return Ω.add(f.Entity, r)
+}
+
+func (Ω *Chronology) Edge(e parse.Edge) error {
+	return nil
+}
+
+func (Ω *Chronology) PredicateSchema(s parse.PredicateSchema) error {
+	return nil
+}
+
+// chronologyKey orders dates as strings. The year is offset so that years before year zero, which
+// Wikidata values can reach billions of, sort before later ones, followed by the rest of the date.
+func chronologyKey(year int, date string) string {
+	rest := ""
+	if i := strings.Index(strings.TrimPrefix(date, "-"), "-"); i >= 0 {
+		rest = strings.TrimPrefix(date, "-")[i:]
+	}
+	if year < 0 {
+		return fmt.Sprintf("0%012d%s", int64(1e12)+int64(year), rest)
+	}
+	return fmt.Sprintf("1%012d%s", year, rest)
+}
+
+// chronologyEntity assembles the event of an entity from its records.
+type chronologyEntity struct {
+	event       ChronologyEvent
+	key         string
+	rank        int
+	label       int
+	description int
+	found       bool
+	// full is true if the date is a Wikidata time value rather than a year.
+	full bool
+}
+
+// languageRank prefers values without a language, then in English.
+func languageRank(lang string) int {
+	switch lang {
+	case "":
+		return 0
+	case "en":
+		return 1
+	}
+	return 2
+}
+
+func (Ω *chronologyEntity) add(r chronologyRecord) {
+	switch r.Kind {
+	case "type":
+		// Records are sorted, so a repeated type follows the first.
+		if n := len(Ω.event.Types); n == 0 || Ω.event.Types[n-1] != r.Value {
+			Ω.event.Types = append(Ω.event.Types, r.Value)
+		}
+	case "label":
+		if rank := languageRank(r.Lang); Ω.event.Label == "" || rank < Ω.label {
+			Ω.event.Label, Ω.label = r.Value, rank
+		}
+	case "description":
+		if rank := languageRank(r.Lang); Ω.event.Description == "" || rank < Ω.description {
+			Ω.event.Description, Ω.description = r.Value, rank
+		}
+	case "date":
+		year, err := strconv.Atoi(r.Value)
+		if err != nil {
+			return
+		}
+		date := r.Date
+		if date == "" {
+			date = r.Value
+		}
+		key := chronologyKey(year, date)
+		if Ω.found && !Ω.before(r, year, key) {
+			return
+		}
+		Ω.found, Ω.rank, Ω.key, Ω.full = true, r.Rank, key, r.Date != ""
+		Ω.event.Date, Ω.event.Year, Ω.event.Precision, Ω.event.Property = date, year, r.Precision, r.Property
+	}
+}
+
+// before is true if the date should replace the current one, which it does if its property is
+// preferred, or if it is earlier, or if it is the same year with a full date rather than the year.
+func (Ω *chronologyEntity) before(r chronologyRecord, year int, key string) bool {
+	if r.Rank != Ω.rank {
+		return r.Rank < Ω.rank
+	}
+	if year != Ω.event.Year {
+		return year < Ω.event.Year
+	}
+	if full := r.Date != ""; full != Ω.full {
+		return full
+	}
+	return key < Ω.key
+}
+
+// write adds the event to the date sorter, if the entity has a date. Ties are broken by ID.
+func (Ω *chronologyEntity) write(events *extsort.Sorter) error {
+	if !Ω.found {
+		return nil
+	}
+	if Ω.event.Label == "" {
+		Ω.event.Label = string(Ω.event.ID)
+	}
+	if Ω.event.Types == nil {
+		Ω.event.Types = []string{}
+	}
+	b, err := json.Marshal(Ω.event)
+	if err != nil {
+		return errors.Wrapf(err, "could not encode event of %s", Ω.event.ID)
+	}
+	return events.Add(Ω.key + "\t" + string(Ω.event.ID) + "\t" + string(b))
+}
+
+// Abort removes the events held for sorting.
+func (Ω *Chronology) Abort() {
+	Ω.entities.Abort()
+}
+
+func (Ω *Chronology) Close() error {
+	events := extsort.New(Ω.chunkSize)
+	// Removes the events if the entities fail to assemble before they are sorted.
+	defer events.Abort()
+	var current *chronologyEntity
+	if err := Ω.entities.Sort(func(line string) error {
+		fields := strings.SplitN(line, "\t", 2)
+		if current == nil || string(current.event.ID) != fields[0] {
+			if current != nil {
+				if err := current.write(events); err != nil {
+					return err
+				}
+			}
+			current = &chronologyEntity{event: ChronologyEvent{ID: parse.EntityID(fields[0])}}
+		}
+		r := chronologyRecord{}
+		if err := json.Unmarshal([]byte(fields[1]), &r); err != nil {
+			return errors.Wrapf(err, "could not decode record of %s", fields[0])
+		}
+		current.add(r)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if current != nil {
+		if err := current.write(events); err != nil {
+			return err
+		}
+	}
+
+	buffered := bufio.NewWriter(Ω.writer)
+	if err := events.Sort(func(line string) error {
+		// Encoded JSON has no raw tabs, so the event is the third field.
+		_, err := buffered.WriteString(strings.SplitN(line, "\t", 3)[2] + "\n")
+		return errors.Wrap(err, "could not write event")
+	}); err != nil {
+		return err
+	}
+	return errors.Wrap(buffered.Flush(), "could not write events")
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// DefaultChunkSize is the number of entities held in memory before the DgraphJSON sink writes them.
+const DefaultChunkSize = 1000
+
+// DgraphJSON writes events as line delimited Dgraph JSON mutations, with one object per entity
+// holding its features, types and nested edge references, and facets as "predicate|facet" keys.
+//
+// Entities are held until the chunk is full, and an entity that receives events after its chunk is
+// written appears again in a later one. Dgraph's live loader merges these by their blank node uid.
+type DgraphJSON struct {
+	sync.Mutex
+	writer    io.Writer
+	schema    *parse.DgraphSchema
+	chunkSize int
+	nodes     map[parse.EntityID]*dgraphNode
+}
+
+func NewDgraphJSON(writer io.Writer, schema *parse.DgraphSchema, chunkSize int) *DgraphJSON {
+	return &DgraphJSON{
+		writer:    writer,
+		schema:    schema,
+		chunkSize: chunkSize,
+		nodes:     map[parse.EntityID]*dgraphNode{},
+	}
+}
+
+type dgraphNode struct {
+	types  map[parse.Predicate]struct{}
+	values map[string]interface{}
+	edges  map[parse.Predicate]map[parse.EntityID][]parse.Facet
+}
+
+// object flattens the node into a single mutation object.
+func (Ω *dgraphNode) object(id parse.EntityID) map[string]interface{} {
+	o := map[string]interface{}{
+		"uid": "_:" + string(id),
+	}
+	for k, v := range Ω.values {
+		o[k] = v
+	}
+	if len(Ω.types) > 0 {
+		types := []string{}
+		for t := range Ω.types {
+			types = append(types, string(t))
+			// Keep the empty type predicates of the RDF output.
+			o[string(t)] = ""
+		}
+		sort.Strings(types)
+		o["dgraph.type"] = types
+	}
+	for predicate, subjects := range Ω.edges {
+		refs := []map[string]interface{}{}
+		for subject, facets := range subjects {
+			ref := map[string]interface{}{"uid": "_:" + string(subject)}
+			for _, f := range facets {
+				ref[string(predicate)+"|"+f.Key] = jsonValue(f.Value, f.Type)
+			}
+			refs = append(refs, ref)
+		}
+		sort.Slice(refs, func(i, j int) bool {
+			return refs[i]["uid"].(string) < refs[j]["uid"].(string)
+		})
+		o[string(predicate)] = refs
+	}
+	return o
+}
+
+// jsonValue converts the literal into the JSON type Dgraph expects for the schema type.
+func jsonValue(v string, t parse.SchemaType) interface{} {
+	switch t {
+	case parse.SchemaTypeInt:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+	case parse.SchemaTypeFloat:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case parse.SchemaTypeBool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case parse.SchemaTypeGeo:
+		if json.Valid([]byte(v)) {
+			return json.RawMessage(v)
+		}
+	}
+	return v
+}
+
+// node returns the buffered node, writing the chunk first if a new node would overflow it.
+// The caller must hold the lock.
+func (Ω *DgraphJSON) node(id parse.EntityID) (*dgraphNode, error) {
+	if n, ok := Ω.nodes[id]; ok {
+		return n, nil
+	}
+	if len(Ω.nodes) >= Ω.chunkSize {
+		if err := Ω.flush(); err != nil {
+			return nil, err
+		}
+	}
+	n := &dgraphNode{
+		types:  map[parse.Predicate]struct{}{},
+		values: map[string]interface{}{},
+		edges:  map[parse.Predicate]map[parse.EntityID][]parse.Facet{},
+	}
+	Ω.nodes[id] = n
+	return n, nil
+}
+
+func (Ω *DgraphJSON) flush() error {
+	ids := make([]string, 0, len(Ω.nodes))
+	for id := range Ω.nodes {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		b, err := json.Marshal(Ω.nodes[parse.EntityID(id)].object(parse.EntityID(id)))
+		if err != nil {
+			return errors.Wrapf(err, "could not marshal node [%s]", id)
+		}
+		if _, err := Ω.writer.Write(append(b, '\n')); err != nil {
+			return errors.Wrapf(err, "could not write node [%s]", id)
+		}
+	}
+	Ω.nodes = map[parse.EntityID]*dgraphNode{}
+	return nil
+}
+
+func (Ω *DgraphJSON) EntityNode(n parse.EntityNode) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	node, err := Ω.node(n.ID)
+	if err != nil {
+		return err
+	}
+	if n.Type != "" {
+		node.types[n.Type] = struct{}{}
+		Ω.schema.Node(n)
+	}
+	return nil
+}
+
+func (Ω *DgraphJSON) Feature(f parse.Feature) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	node, err := Ω.node(f.Entity)
+	if err != nil {
+		return err
+	}
+	Ω.schema.Field(f.EntityType, f.Predicate)
+	key := string(f.Predicate)
+	if lang := f.DgraphLang(); lang != "" {
+		key += "@" + lang
+	}
+	if f.Type == parse.SchemaTypeStringList {
+		list, _ := node.values[key].([]string)
+		for _, v := range list {
+			if v == f.Value {
+				return nil
+			}
+		}
+		node.values[key] = append(list, f.Value)
+		return nil
+	}
+	node.values[key] = jsonValue(f.Value, f.Type)
+	for _, facet := range f.Facets {
+		node.values[key+"|"+facet.Key] = jsonValue(facet.Value, facet.Type)
+	}
+	return nil
+}
+
+func (Ω *DgraphJSON) Edge(e parse.Edge) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	node, err := Ω.node(e.Object)
+	if err != nil {
+		return err
+	}
+	Ω.schema.Field(e.EntityType, e.Predicate)
+	if _, ok := node.edges[e.Predicate]; !ok {
+		node.edges[e.Predicate] = map[parse.EntityID][]parse.Facet{}
+	}
+	node.edges[e.Predicate][e.Subject] = e.Facets
+	return nil
+}
+
+func (Ω *DgraphJSON) PredicateSchema(s parse.PredicateSchema) error {
+	return Ω.schema.Write(s)
+}
+
+// Close writes the remaining chunk and the schema.
+func (Ω *DgraphJSON) Close() error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	if err := Ω.flush(); err != nil {
+		return err
+	}
+	return Ω.schema.Close()
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// Documents writes one JSON document per line for every entity, with its features grouped by
+// predicate and its edges expanded to stubs of the entities they point to. Documents are sorted by
+// start year, with entities without one last. Everything is held in memory until Close.
+type Documents struct {
+	*graph
+	writer io.Writer
+}
+
+func NewDocuments(writer io.Writer) *Documents {
+	return &Documents{graph: newGraph(), writer: writer}
+}
+
+type documentValue struct {
+	Value  interface{}            `json:"value"`
+	Lang   string                 `json:"lang,omitempty"`
+	Facets map[string]interface{} `json:"facets,omitempty"`
+}
+
+// documentStub is the entity an edge points to.
+type documentStub struct {
+	ID     parse.EntityID         `json:"id"`
+	Label  string                 `json:"label"`
+	Type   string                 `json:"type,omitempty"`
+	Facets map[string]interface{} `json:"facets,omitempty"`
+}
+
+type document struct {
+	ID        parse.EntityID                      `json:"id"`
+	Label     string                              `json:"label"`
+	Types     []string                            `json:"types"`
+	StartYear *int                                `json:"start_year,omitempty"`
+	EndYear   *int                                `json:"end_year,omitempty"`
+	Features  map[parse.Predicate][]documentValue `json:"features"`
+	Edges     map[parse.Predicate][]documentStub  `json:"edges"`
+}
+
+func documentFacets(facets []parse.Facet) map[string]interface{} {
+	if len(facets) == 0 {
+		return nil
+	}
+	res := map[string]interface{}{}
+	for _, f := range facets {
+		res[f.Key] = jsonValue(f.Value, f.Type)
+	}
+	return res
+}
+
+func (Ω *Documents) document(n *node) document {
+	d := document{
+		ID:        n.ID,
+		Label:     n.Label(),
+		Types:     []string{},
+		StartYear: n.StartYear(),
+		EndYear:   n.EndYear(),
+		Features:  map[parse.Predicate][]documentValue{},
+		Edges:     map[parse.Predicate][]documentStub{},
+	}
+	for _, t := range n.Types {
+		d.Types = append(d.Types, strings.TrimPrefix(string(t), "t_"))
+	}
+	for p, features := range n.Features {
+		for _, f := range features {
+			d.Features[p] = append(d.Features[p], documentValue{
+				Value:  jsonValue(f.Value, f.Type),
+				Lang:   f.Lang,
+				Facets: documentFacets(f.Facets),
+			})
+		}
+	}
+	for _, e := range n.Edges {
+		stub := documentStub{ID: e.Subject, Label: string(e.Subject), Facets: documentFacets(e.Facets)}
+		if s := Ω.Node(e.Subject); s != nil {
+			stub.Label = s.Label()
+			if len(s.Types) > 0 {
+				stub.Type = strings.TrimPrefix(string(s.Types[0]), "t_")
+			}
+		}
+		d.Edges[e.Predicate] = append(d.Edges[e.Predicate], stub)
+	}
+	return d
+}
+
+func (Ω *Documents) Close() error {
+	documents := []document{}
+	for _, n := range Ω.Nodes() {
+		if n.ID.IsReference() {
+			continue
+		}
+		documents = append(documents, Ω.document(n))
+	}
+	// Nodes are sorted by ID, which the stable sort keeps between documents of the same year.
+	sort.SliceStable(documents, func(i, j int) bool {
+		a, b := documents[i].StartYear, documents[j].StartYear
+		return a != nil && (b == nil || *a < *b)
+	})
+
+	buffered := bufio.NewWriter(Ω.writer)
+	encoder := json.NewEncoder(buffered)
+	for _, d := range documents {
+		if err := encoder.Encode(d); err != nil {
+			return errors.Wrapf(err, "could not write document of %s", d.ID)
+		}
+	}
+	return errors.Wrap(buffered.Flush(), "could not write documents")
+}
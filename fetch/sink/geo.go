@@ -0,0 +1,183 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// locatedEvent is an entity with coordinates, along with the properties written for it on a map.
+type locatedEvent struct {
+	node         *node
+	geometry     string
+	start, end   *int
+	participants []string
+}
+
+// locatedEvents returns every entity of the graph that has coordinates, sorted by ID.
+func locatedEvents(g *graph) []locatedEvent {
+	res := []locatedEvent{}
+	for _, n := range g.Nodes() {
+		geometry, ok := n.Geometry()
+		if !ok {
+			continue
+		}
+		e := locatedEvent{node: n, geometry: geometry, start: n.StartYear(), end: n.EndYear(), participants: []string{}}
+		for _, p := range g.Participants(n) {
+			e.participants = append(e.participants, p.Label())
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+func (Ω *locatedEvent) types() []string {
+	res := make([]string, len(Ω.node.Types))
+	for i, t := range Ω.node.Types {
+		res[i] = strings.TrimPrefix(string(t), "t_")
+	}
+	return res
+}
+
+// GeoJSON writes a FeatureCollection of every entity with coordinates, with its label, types,
+// start and end year and participants as properties. Everything is held in memory until Close.
+type GeoJSON struct {
+	*graph
+	writer io.Writer
+}
+
+func NewGeoJSON(writer io.Writer) *GeoJSON {
+	return &GeoJSON{graph: newGraph(), writer: writer}
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         parse.EntityID         `json:"id"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+func (Ω *GeoJSON) Close() error {
+	collection := struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+
+	for _, e := range locatedEvents(Ω.graph) {
+		properties := map[string]interface{}{
+			"label":        e.node.Label(),
+			"types":        e.types(),
+			"participants": e.participants,
+		}
+		if d := e.node.Value(parse.PredicateDescription, "en"); d != "" {
+			properties["description"] = d
+		}
+		if e.start != nil {
+			properties["start_year"] = *e.start
+		}
+		if e.end != nil {
+			properties["end_year"] = *e.end
+		}
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			ID:         e.node.ID,
+			Geometry:   json.RawMessage(e.geometry),
+			Properties: properties,
+		})
+	}
+
+	b, err := json.Marshal(collection)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal feature collection")
+	}
+	_, err = Ω.writer.Write(append(b, '\n'))
+	return errors.Wrap(err, "could not write feature collection")
+}
+
+// KML writes a placemark for every entity with coordinates, with a TimeSpan from its start and end
+// year so that Google Earth can animate it. Everything is held in memory until Close.
+type KML struct {
+	*graph
+	writer io.Writer
+}
+
+func NewKML(writer io.Writer) *KML {
+	return &KML{graph: newGraph(), writer: writer}
+}
+
+// xsdYear formats the year as an XML Schema gYear, which has at least four digits and a leading
+// minus sign for years before year zero.
+func xsdYear(y int) string {
+	if y < 0 {
+		return fmt.Sprintf("-%04d", -y)
+	}
+	return fmt.Sprintf("%04d", y)
+}
+
+type kmlPlacemark struct {
+	ID           string       `xml:"id,attr"`
+	Name         string       `xml:"name"`
+	Description  string       `xml:"description,omitempty"`
+	TimeSpan     *kmlTimeSpan `xml:"TimeSpan,omitempty"`
+	ExtendedData []kmlData    `xml:"ExtendedData>Data,omitempty"`
+	Coordinates  string       `xml:"Point>coordinates"`
+}
+
+type kmlTimeSpan struct {
+	Begin string `xml:"begin,omitempty"`
+	End   string `xml:"end,omitempty"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+func (Ω *KML) Close() error {
+	document := struct {
+		XMLName    xml.Name       `xml:"kml"`
+		Namespace  string         `xml:"xmlns,attr"`
+		Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+	}{Namespace: "http://www.opengis.net/kml/2.2"}
+
+	for _, e := range locatedEvents(Ω.graph) {
+		lng, lat, ok := geoPoint(e.geometry)
+		if !ok {
+			continue
+		}
+		p := kmlPlacemark{
+			ID:          string(e.node.ID),
+			Name:        e.node.Label(),
+			Description: e.node.Value(parse.PredicateDescription, "en"),
+			Coordinates: fmt.Sprintf("%f,%f", lng, lat),
+		}
+		if e.start != nil || e.end != nil {
+			p.TimeSpan = &kmlTimeSpan{}
+			if e.start != nil {
+				p.TimeSpan.Begin = xsdYear(*e.start)
+			}
+			if e.end != nil {
+				p.TimeSpan.End = xsdYear(*e.end)
+			}
+		}
+		if types := e.types(); len(types) > 0 {
+			p.ExtendedData = append(p.ExtendedData, kmlData{Name: "types", Value: strings.Join(types, ", ")})
+		}
+		if len(e.participants) > 0 {
+			p.ExtendedData = append(p.ExtendedData, kmlData{Name: "participants", Value: strings.Join(e.participants, ", ")})
+		}
+		document.Placemarks = append(document.Placemarks, p)
+	}
+
+	return writeXML(Ω.writer, document, "kml")
+}
@@ -0,0 +1,328 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/heindl/wikivents/fetch/parse"
+)
+
+// CreateFunc opens a named output file, for sinks that write more than one file.
+type CreateFunc func(name string) (io.Writer, error)
+
+// The start and end of an event are read from these properties in order of preference, and from
+// the predicates of their English labels when predicates are not named by property ID.
+var (
+	startProperties = []string{"P585", "P580", "P571", "P569"}
+	endProperties   = []string{"P582", "P576", "P570"}
+	startPredicates = []parse.Predicate{"f_point_in_time", "f_start_time", "f_inception", "f_date_of_birth"}
+	endPredicates   = []parse.Predicate{"f_end_time", "f_dissolved_abolished_or_demolished_date", "f_date_of_death"}
+)
+
+// Participants are read from the edges of these properties, or of their predicates.
+var (
+	participantProperties = []string{"P710", "P1923"}
+	participantPredicates = []parse.Predicate{"e_participant", "e_participating_team"}
+)
+
+// geoPoint reads the longitude and latitude of a GeoJSON point.
+func geoPoint(v string) (lng, lat float64, ok bool) {
+	gj := struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	}{}
+	if err := json.Unmarshal([]byte(v), &gj); err != nil || gj.Type != "Point" || len(gj.Coordinates) != 2 {
+		return 0, 0, false
+	}
+	return gj.Coordinates[0], gj.Coordinates[1], true
+}
+
+// node holds every event received for one entity.
+type node struct {
+	ID    parse.EntityID
+	Types []parse.Predicate
+	// Features are grouped by predicate, in the order received, without repeated values.
+	Features map[parse.Predicate][]parse.Feature
+	Edges    []parse.Edge
+}
+
+// Values returns the values of the predicate.
+func (Ω *node) Values(p parse.Predicate) []string {
+	res := []string{}
+	for _, f := range Ω.Features[p] {
+		res = append(res, f.Value)
+	}
+	return res
+}
+
+// Value returns the first value of the predicate, preferring one without a language tag or in
+// the first of the given languages.
+func (Ω *node) Value(p parse.Predicate, langs ...string) string {
+	features := Ω.Features[p]
+	for _, lang := range append([]string{""}, langs...) {
+		for _, f := range features {
+			if f.Lang == lang {
+				return f.Value
+			}
+		}
+	}
+	if len(features) > 0 {
+		return features[0].Value
+	}
+	return ""
+}
+
+// Label is the label of the entity, falling back to its ID.
+func (Ω *node) Label() string {
+	if l := Ω.Value(parse.PredicateLabel, "en"); l != "" {
+		return l
+	}
+	return string(Ω.ID)
+}
+
+// matches returns the index of the property the feature or edge came from, or of its predicate,
+// or -1 if it matches neither.
+func matches(property string, predicate parse.Predicate, properties []string, predicates []parse.Predicate) int {
+	for i := range properties {
+		if property == properties[i] || predicate == predicates[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// date returns the feature of the most preferred property that the node has, taking the earliest
+// of its years, or the latest if latest is true.
+func (Ω *node) date(properties []string, predicates []parse.Predicate, latest bool) (*parse.Feature, int) {
+	var res *parse.Feature
+	var year int
+	best := len(properties)
+	for _, features := range Ω.Features {
+		for i := range features {
+			f := &features[i]
+			rank := matches(f.Property, f.Predicate, properties, predicates)
+			if rank < 0 || rank > best || f.Type != parse.SchemaTypeInt {
+				continue
+			}
+			y, err := strconv.Atoi(f.Value)
+			if err != nil {
+				continue
+			}
+			if rank < best || res == nil || (latest && y > year) || (!latest && y < year) {
+				best, res, year = rank, f, y
+			}
+		}
+	}
+	return res, year
+}
+
+// year returns the year of the date, if the node has one.
+func (Ω *node) year(properties []string, predicates []parse.Predicate, latest bool) *int {
+	f, year := Ω.date(properties, predicates, latest)
+	if f == nil {
+		return nil
+	}
+	return &year
+}
+
+// StartYear is the year the event happened or began, if known.
+func (Ω *node) StartYear() *int {
+	return Ω.year(startProperties, startPredicates, false)
+}
+
+// EndYear is the year the event ended, if known.
+func (Ω *node) EndYear() *int {
+	return Ω.year(endProperties, endPredicates, true)
+}
+
+// eventDate is a date to the precision it is known at, so Month and Day are zero when unknown.
+// Years follow XSD 1.1, as Wikidata does, so that year 0 is 1 BCE and year -43 is 44 BCE.
+type eventDate struct {
+	Year, Month, Day int
+}
+
+// newEventDate reads the date and precision facets of a time feature, falling back to its year.
+func newEventDate(f *parse.Feature, year int) *eventDate {
+	d := &eventDate{Year: year}
+	value, precision := "", -1
+	for _, facet := range f.Facets {
+		switch facet.Key {
+		case "date":
+			value = facet.Value
+		case "precision":
+			if p, err := strconv.Atoi(facet.Value); err == nil {
+				precision = p
+			}
+		}
+	}
+	// The year is negative before year zero, so the month and day are the last two fields.
+	fields := strings.Split(strings.SplitN(value, "T", 2)[0], "-")
+	if len(fields) < 3 {
+		return d
+	}
+	if month, err := strconv.Atoi(fields[len(fields)-2]); err == nil && precision >= 10 {
+		d.Month = month
+	}
+	if day, err := strconv.Atoi(fields[len(fields)-1]); err == nil && d.Month > 0 && precision >= 11 {
+		d.Day = day
+	}
+	return d
+}
+
+// StartDate is the date the event happened or began, if known.
+func (Ω *node) StartDate() *eventDate {
+	f, year := Ω.date(startProperties, startPredicates, false)
+	if f == nil {
+		return nil
+	}
+	return newEventDate(f, year)
+}
+
+// EndDate is the date the event ended, if known.
+func (Ω *node) EndDate() *eventDate {
+	f, year := Ω.date(endProperties, endPredicates, true)
+	if f == nil {
+		return nil
+	}
+	return newEventDate(f, year)
+}
+
+// Geometry returns the first GeoJSON value of the node, if it has one.
+func (Ω *node) Geometry() (string, bool) {
+	for _, p := range Ω.Predicates() {
+		for _, f := range Ω.Features[p] {
+			if f.Type == parse.SchemaTypeGeo {
+				return f.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Predicates returns the feature predicates of the node, sorted.
+func (Ω *node) Predicates() []parse.Predicate {
+	res := make([]parse.Predicate, 0, len(Ω.Features))
+	for p := range Ω.Features {
+		res = append(res, p)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// graph collects events by entity for the formats that can only be written once every event has
+// been received. It implements every Sink method but Close, which the format provides.
+type graph struct {
+	sync.Mutex
+	nodes   map[parse.EntityID]*node
+	schemas map[parse.Predicate]parse.PredicateSchema
+}
+
+func newGraph() *graph {
+	return &graph{
+		nodes:   map[parse.EntityID]*node{},
+		schemas: map[parse.Predicate]parse.PredicateSchema{},
+	}
+}
+
+// node returns the node of the entity, creating it if needed. The caller must hold the lock.
+func (Ω *graph) node(id parse.EntityID) *node {
+	n, ok := Ω.nodes[id]
+	if !ok {
+		n = &node{ID: id, Features: map[parse.Predicate][]parse.Feature{}}
+		Ω.nodes[id] = n
+	}
+	return n
+}
+
+func (Ω *graph) EntityNode(e parse.EntityNode) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	n := Ω.node(e.ID)
+	if e.Type == "" {
+		return nil
+	}
+	for _, t := range n.Types {
+		if t == e.Type {
+			return nil
+		}
+	}
+	n.Types = append(n.Types, e.Type)
+	sort.Slice(n.Types, func(i, j int) bool { return n.Types[i] < n.Types[j] })
+	return nil
+}
+
+func (Ω *graph) Feature(f parse.Feature) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	n := Ω.node(f.Entity)
+	for _, existing := range n.Features[f.Predicate] {
+		if existing.Value == f.Value && existing.Lang == f.Lang {
+			return nil
+		}
+	}
+	n.Features[f.Predicate] = append(n.Features[f.Predicate], f)
+	return nil
+}
+
+func (Ω *graph) Edge(e parse.Edge) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	n := Ω.node(e.Object)
+	for _, existing := range n.Edges {
+		if existing.Predicate == e.Predicate && existing.Subject == e.Subject {
+			return nil
+		}
+	}
+	n.Edges = append(n.Edges, e)
+	return nil
+}
+
+func (Ω *graph) PredicateSchema(s parse.PredicateSchema) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.schemas[s.Predicate] = s
+	return nil
+}
+
+// Nodes returns every node, sorted by ID.
+func (Ω *graph) Nodes() []*node {
+	Ω.Lock()
+	defer Ω.Unlock()
+	res := make([]*node, 0, len(Ω.nodes))
+	for _, n := range Ω.nodes {
+		res = append(res, n)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// Participants returns the participant nodes of the node, sorted by ID.
+func (Ω *graph) Participants(n *node) []*node {
+	res := []*node{}
+	for _, e := range n.Edges {
+		if matches(e.Property, e.Predicate, participantProperties, participantPredicates) < 0 {
+			continue
+		}
+		if p := Ω.Node(e.Subject); p != nil {
+			res = append(res, p)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// Node returns the node of the entity, or nil if no event was received for it.
+func (Ω *graph) Node(id parse.EntityID) *node {
+	Ω.Lock()
+	defer Ω.Unlock()
+	return Ω.nodes[id]
+}
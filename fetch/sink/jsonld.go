@@ -0,0 +1,131 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// JSONLD writes events as a JSON-LD document with one node object per statement in its @graph,
+// which JSON-LD processors merge by @id. The @context maps the f_, e_ and t_ predicates to the
+// namespace through @vocab. As with Turtle, facets are dropped.
+type JSONLD struct {
+	sync.Mutex
+	m         *sync.Map
+	writer    io.Writer
+	namespace string
+	started   bool
+}
+
+func NewJSONLD(writer io.Writer, namespace string) *JSONLD {
+	return &JSONLD{
+		m:         new(sync.Map),
+		writer:    writer,
+		namespace: namespace,
+	}
+}
+
+type jsonldValue struct {
+	Value    string `json:"@value"`
+	Language string `json:"@language,omitempty"`
+	Type     string `json:"@type,omitempty"`
+}
+
+type jsonldReference struct {
+	ID string `json:"@id"`
+}
+
+func (Ω *JSONLD) context() map[string]interface{} {
+	return map[string]interface{}{
+		"@vocab": Ω.namespace,
+		"wd":     entityNamespace,
+		"wdref":  referenceNamespace,
+		"wds":    statementNamespace,
+	}
+}
+
+func (Ω *JSONLD) write(node map[string]interface{}) error {
+	b, err := json.Marshal(node)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal json-ld node")
+	}
+	if _, ok := Ω.m.LoadOrStore(string(b), struct{}{}); ok {
+		return nil
+	}
+	Ω.Lock()
+	defer Ω.Unlock()
+	separator := ",\n"
+	if !Ω.started {
+		Ω.started = true
+		context, err := json.Marshal(Ω.context())
+		if err != nil {
+			return errors.Wrap(err, "could not marshal json-ld context")
+		}
+		separator = `{"@context":` + string(context) + ",\n" + `"@graph":[` + "\n"
+	}
+	if _, err := io.WriteString(Ω.writer, separator+string(b)); err != nil {
+		return errors.Wrap(err, "could not write json-ld node")
+	}
+	return nil
+}
+
+func (Ω *JSONLD) EntityNode(n parse.EntityNode) error {
+	if n.Type == "" {
+		return nil
+	}
+	return Ω.write(map[string]interface{}{
+		"@id":   curie(n.ID),
+		"@type": string(n.Type),
+	})
+}
+
+func (Ω *JSONLD) Feature(f parse.Feature) error {
+	v := jsonldValue{Value: f.Value}
+	if datatype, ok := datatypes[f.Type]; ok {
+		v.Type = datatype
+	} else {
+		v.Language = f.Lang
+	}
+	return Ω.write(map[string]interface{}{
+		"@id":               curie(f.Entity),
+		string(f.Predicate): v,
+	})
+}
+
+func (Ω *JSONLD) Edge(e parse.Edge) error {
+	return Ω.write(map[string]interface{}{
+		"@id":               curie(e.Object),
+		string(e.Predicate): jsonldReference{ID: curie(e.Subject)},
+	})
+}
+
+// PredicateSchema is ignored, as literal types are written with each value.
+func (Ω *JSONLD) PredicateSchema(parse.PredicateSchema) error {
+	return nil
+}
+
+// Close ends the @graph array, or writes an empty document if no statements were received.
+func (Ω *JSONLD) Close() error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	end := "\n]}\n"
+	if !Ω.started {
+		context, err := json.Marshal(Ω.context())
+		if err != nil {
+			return errors.Wrap(err, "could not marshal json-ld context")
+		}
+		end = `{"@context":` + string(context) + `,"@graph":[]}` + "\n"
+	}
+	if _, err := io.WriteString(Ω.writer, end); err != nil {
+		return errors.Wrap(err, "could not close json-ld document")
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// neo4jTypes maps schema types to the header types of neo4j-admin import.
+var neo4jTypes = map[parse.SchemaType]string{
+	parse.SchemaTypeInt:        "long",
+	parse.SchemaTypeFloat:      "double",
+	parse.SchemaTypeBool:       "boolean",
+	parse.SchemaTypeDateTime:   "datetime",
+	parse.SchemaTypeGeo:        "point",
+	parse.SchemaTypeString:     "string",
+	parse.SchemaTypeStringList: "string[]",
+}
+
+// neo4jArrayDelimiter separates the values of array properties.
+const neo4jArrayDelimiter = ";"
+
+// Neo4jCSV writes the CSV files of a neo4j-admin bulk import, along with an import.sh script that
+// runs it. Each node is written once, to the file of its first t_ label, with every label in its
+// :LABEL column and its f_ features as properties. Each e_ predicate has a relationship file, with
+// facets as relationship properties. Everything is held in memory until Close.
+type Neo4jCSV struct {
+	*graph
+	create CreateFunc
+}
+
+func NewNeo4jCSV(create CreateFunc) *Neo4jCSV {
+	return &Neo4jCSV{graph: newGraph(), create: create}
+}
+
+// neo4jProperty is the property name of a feature. Language tagged values are written to a
+// property for each language, like f_label@de, with the untagged property holding the first value.
+func neo4jProperty(f parse.Feature) string {
+	if f.Lang == "" {
+		return string(f.Predicate)
+	}
+	return string(f.Predicate) + "@" + f.Lang
+}
+
+// neo4jValue formats the value for its header type. Coordinates are converted from GeoJSON.
+func neo4jValue(v string, t parse.SchemaType) string {
+	if t != parse.SchemaTypeGeo {
+		return v
+	}
+	lng, lat, ok := geoPoint(v)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("{longitude:%f, latitude:%f}", lng, lat)
+}
+
+// neo4jHeader is the typed header of a property column.
+func neo4jHeader(name string, t parse.SchemaType) string {
+	if nt, ok := neo4jTypes[t]; ok {
+		return name + ":" + nt
+	}
+	return name
+}
+
+// neo4jFile collects the rows of one CSV file until its columns are known.
+type neo4jFile struct {
+	name    string
+	fixed   []string
+	columns columns
+	rows    [][]string
+	values  []map[string]string
+}
+
+func newNeo4jFile(name string, fixed ...string) *neo4jFile {
+	return &neo4jFile{name: name, fixed: fixed, columns: columns{}}
+}
+
+func (Ω *neo4jFile) add(row []string, values map[string]string) {
+	Ω.rows = append(Ω.rows, row)
+	Ω.values = append(Ω.values, values)
+}
+
+func (Ω *neo4jFile) write(create CreateFunc) error {
+	w, err := create(Ω.name)
+	if err != nil {
+		return err
+	}
+	columns := Ω.columns.sorted()
+
+	c := csv.NewWriter(w)
+	header := append([]string{}, Ω.fixed...)
+	for _, column := range columns {
+		header = append(header, neo4jHeader(column.name, column.t))
+	}
+	if err := c.Write(header); err != nil {
+		return errors.Wrapf(err, "could not write header of %s", Ω.name)
+	}
+	for i, row := range Ω.rows {
+		for _, column := range columns {
+			row = append(row, Ω.values[i][column.name])
+		}
+		if err := c.Write(row); err != nil {
+			return errors.Wrapf(err, "could not write row of %s", Ω.name)
+		}
+	}
+	c.Flush()
+	return errors.Wrapf(c.Error(), "could not write %s", Ω.name)
+}
+
+func (Ω *Neo4jCSV) Close() error {
+	nodeFiles := map[string]*neo4jFile{}
+	relationshipFiles := map[parse.Predicate]*neo4jFile{}
+
+	for _, n := range Ω.Nodes() {
+		name := "nodes.csv"
+		if len(n.Types) > 0 {
+			name = fmt.Sprintf("nodes_%s.csv", n.Types[0])
+		}
+		if _, ok := nodeFiles[name]; !ok {
+			nodeFiles[name] = newNeo4jFile(name, "id:ID", ":LABEL")
+		}
+		file := nodeFiles[name]
+
+		labels := make([]string, len(n.Types))
+		for i, t := range n.Types {
+			labels[i] = string(t)
+		}
+		values := map[string]string{}
+		for _, p := range n.Predicates() {
+			for _, f := range n.Features[p] {
+				if f.Type == parse.SchemaTypeStringList {
+					values[string(p)] = strings.Join(n.Values(p), neo4jArrayDelimiter)
+					file.columns.add(string(p), f.Type)
+					break
+				}
+				if _, ok := values[string(p)]; !ok {
+					values[string(p)] = neo4jValue(f.Value, f.Type)
+					file.columns.add(string(p), f.Type)
+				}
+				if property := neo4jProperty(f); f.Lang != "" {
+					if _, ok := values[property]; !ok {
+						values[property] = f.Value
+						file.columns.add(property, parse.SchemaTypeString)
+					}
+				}
+			}
+		}
+		file.add([]string{string(n.ID), strings.Join(labels, neo4jArrayDelimiter)}, values)
+
+		for _, e := range n.Edges {
+			if _, ok := relationshipFiles[e.Predicate]; !ok {
+				relationshipFiles[e.Predicate] = newNeo4jFile(
+					fmt.Sprintf("relationships_%s.csv", e.Predicate),
+					":START_ID", ":END_ID", ":TYPE",
+				)
+			}
+			file := relationshipFiles[e.Predicate]
+			values := map[string]string{}
+			for _, facet := range e.Facets {
+				values[facet.Key] = facet.Value
+				file.columns.add(facet.Key, facet.Type)
+			}
+			file.add([]string{string(e.Object), string(e.Subject), string(e.Predicate)}, values)
+		}
+	}
+
+	script := []string{
+		"#!/bin/sh",
+		"# Bulk imports the wikivents CSV files into a new Neo4j database, named by the first argument.",
+		`cd "$(dirname "$0")"`,
+		"neo4j-admin database import full \\",
+	}
+	names := []string{}
+	for name := range nodeFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := nodeFiles[name].write(Ω.create); err != nil {
+			return err
+		}
+		script = append(script, fmt.Sprintf("  --nodes=%s \\", name))
+	}
+	predicates := []string{}
+	for p := range relationshipFiles {
+		predicates = append(predicates, string(p))
+	}
+	sort.Strings(predicates)
+	for _, p := range predicates {
+		file := relationshipFiles[parse.Predicate(p)]
+		if err := file.write(Ω.create); err != nil {
+			return err
+		}
+		script = append(script, fmt.Sprintf("  --relationships=%s \\", file.name))
+	}
+	script = append(script,
+		fmt.Sprintf(`  --array-delimiter="%s" \`, neo4jArrayDelimiter),
+		"  --multiline-fields=true \\",
+		"  --skip-bad-relationships=true \\",
+		`  "${1:-neo4j}"`,
+		"",
+	)
+
+	w, err := Ω.create("import.sh")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte(strings.Join(script, "\n")))
+	return errors.Wrap(err, "could not write import.sh")
+}
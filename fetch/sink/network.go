@@ -0,0 +1,343 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// networkAttribute is a column of node or edge attributes, named by predicate or facet key.
+type networkAttribute struct {
+	name string
+	t    parse.SchemaType
+}
+
+// networkElement is a node or edge with its attribute values by column name.
+type networkElement struct {
+	id, label      string
+	source, target string
+	kind           string
+	start, end     *int
+	values         map[string]string
+}
+
+// network is the graph as nodes and edges with typed attribute columns, which both GEXF and
+// GraphML are written from.
+type network struct {
+	nodeAttributes, edgeAttributes []networkAttribute
+	nodes, edges                   []networkElement
+}
+
+// networkTypes is the name of the column of t_ types.
+const networkTypes = "types"
+
+// networkListDelimiter separates the values of list attributes, as GEXF expects.
+const networkListDelimiter = "|"
+
+// columns collects the type of each column, falling back to string when the values disagree.
+type columns map[string]parse.SchemaType
+
+func (Ω columns) add(name string, t parse.SchemaType) {
+	if existing, ok := Ω[name]; ok && existing != t {
+		t = parse.SchemaTypeString
+	}
+	Ω[name] = t
+}
+
+func (Ω columns) sorted() []networkAttribute {
+	res := make([]networkAttribute, 0, len(Ω))
+	for name, t := range Ω {
+		res = append(res, networkAttribute{name: name, t: t})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].name < res[j].name })
+	return res
+}
+
+func newNetwork(g *graph) *network {
+	res := &network{}
+	nodeColumns, edgeColumns := columns{}, columns{}
+	for _, n := range g.Nodes() {
+		element := networkElement{id: string(n.ID), label: n.Label(), values: map[string]string{}}
+		element.start, element.end = n.StartYear(), n.EndYear()
+		if element.start != nil && element.end != nil && *element.end < *element.start {
+			element.end = nil
+		}
+		if len(n.Types) > 0 {
+			element.values[networkTypes] = strings.Join(predicateStrings(n.Types), networkListDelimiter)
+			nodeColumns.add(networkTypes, parse.SchemaTypeStringList)
+		}
+		for _, p := range n.Predicates() {
+			t := n.Features[p][0].Type
+			if t == parse.SchemaTypeStringList {
+				element.values[string(p)] = strings.Join(n.Values(p), networkListDelimiter)
+			} else {
+				element.values[string(p)] = n.Value(p, "en")
+			}
+			nodeColumns.add(string(p), t)
+		}
+		res.nodes = append(res.nodes, element)
+
+		for _, e := range n.Edges {
+			if g.Node(e.Subject) == nil {
+				continue
+			}
+			edge := networkElement{
+				id:     fmt.Sprintf("%d", len(res.edges)),
+				source: string(e.Object),
+				target: string(e.Subject),
+				kind:   string(e.Predicate),
+				values: map[string]string{},
+			}
+			for _, f := range e.Facets {
+				edge.values[f.Key] = f.Value
+				edgeColumns.add(f.Key, f.Type)
+			}
+			res.edges = append(res.edges, edge)
+		}
+	}
+	res.nodeAttributes, res.edgeAttributes = nodeColumns.sorted(), edgeColumns.sorted()
+	return res
+}
+
+// writeXML writes the document with an XML header.
+func writeXML(w io.Writer, document interface{}, name string) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.Wrapf(err, "could not write %s", name)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return errors.Wrapf(err, "could not encode %s", name)
+	}
+	_, err := io.WriteString(w, "\n")
+	return errors.Wrapf(err, "could not write %s", name)
+}
+
+// gexfTypes maps schema types to GEXF attribute types. Other values are written as strings.
+var gexfTypes = map[parse.SchemaType]string{
+	parse.SchemaTypeInt:        "long",
+	parse.SchemaTypeFloat:      "double",
+	parse.SchemaTypeBool:       "boolean",
+	parse.SchemaTypeStringList: "liststring",
+}
+
+// GEXF writes a dynamic GEXF graph for Gephi, with entities as nodes and e_ predicates as the kind
+// of their edges. Nodes have their t_ types and f_ features as attributes, and the years they
+// began and ended as the start and end of their spells. Everything is held in memory until Close.
+type GEXF struct {
+	*graph
+	writer io.Writer
+}
+
+func NewGEXF(writer io.Writer) *GEXF {
+	return &GEXF{graph: newGraph(), writer: writer}
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfElement struct {
+	ID     string      `xml:"id,attr"`
+	Label  string      `xml:"label,attr,omitempty"`
+	Source string      `xml:"source,attr,omitempty"`
+	Target string      `xml:"target,attr,omitempty"`
+	Kind   string      `xml:"kind,attr,omitempty"`
+	Start  string      `xml:"start,attr,omitempty"`
+	End    string      `xml:"end,attr,omitempty"`
+	Values []gexfValue `xml:"attvalues>attvalue,omitempty"`
+}
+
+func gexfAttributeList(class string, attributes []networkAttribute) gexfAttributes {
+	res := gexfAttributes{Class: class, Attributes: []gexfAttribute{}}
+	for _, a := range attributes {
+		t, ok := gexfTypes[a.t]
+		if !ok {
+			t = "string"
+		}
+		res.Attributes = append(res.Attributes, gexfAttribute{ID: a.name, Title: a.name, Type: t})
+	}
+	return res
+}
+
+func gexfElements(elements []networkElement, attributes []networkAttribute) []gexfElement {
+	res := make([]gexfElement, 0, len(elements))
+	for _, e := range elements {
+		g := gexfElement{ID: e.id, Label: e.label, Source: e.source, Target: e.target, Kind: e.kind}
+		if e.start != nil {
+			g.Start = strconv.Itoa(*e.start)
+		}
+		if e.end != nil {
+			g.End = strconv.Itoa(*e.end)
+		}
+		for _, a := range attributes {
+			if v, ok := e.values[a.name]; ok {
+				g.Values = append(g.Values, gexfValue{For: a.name, Value: v})
+			}
+		}
+		res = append(res, g)
+	}
+	return res
+}
+
+func (Ω *GEXF) Close() error {
+	n := newNetwork(Ω.graph)
+	// Years are written as doubles, since GEXF dates cannot be before year one.
+	document := struct {
+		XMLName   xml.Name `xml:"gexf"`
+		Namespace string   `xml:"xmlns,attr"`
+		Version   string   `xml:"version,attr"`
+		Graph     struct {
+			DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+			Mode            string           `xml:"mode,attr"`
+			TimeFormat      string           `xml:"timeformat,attr"`
+			Attributes      []gexfAttributes `xml:"attributes"`
+			Nodes           []gexfElement    `xml:"nodes>node"`
+			Edges           []gexfElement    `xml:"edges>edge"`
+		} `xml:"graph"`
+	}{Namespace: "http://gexf.net/1.3", Version: "1.3"}
+	document.Graph.DefaultEdgeType = "directed"
+	document.Graph.Mode = "dynamic"
+	document.Graph.TimeFormat = "double"
+	document.Graph.Attributes = []gexfAttributes{
+		gexfAttributeList("node", n.nodeAttributes),
+		gexfAttributeList("edge", n.edgeAttributes),
+	}
+	document.Graph.Nodes = gexfElements(n.nodes, n.nodeAttributes)
+	document.Graph.Edges = gexfElements(n.edges, n.edgeAttributes)
+	return writeXML(Ω.writer, document, "gexf")
+}
+
+// graphMLTypes maps schema types to GraphML attribute types. Other values are written as strings.
+var graphMLTypes = map[parse.SchemaType]string{
+	parse.SchemaTypeInt:   "long",
+	parse.SchemaTypeFloat: "double",
+	parse.SchemaTypeBool:  "boolean",
+}
+
+// GraphML writes a GraphML graph for NetworkX and other tools, with entities as nodes and e_
+// predicates as the predicate of their edges. Nodes have their label, t_ types, f_ features and
+// start and end years as data. Everything is held in memory until Close.
+type GraphML struct {
+	*graph
+	writer io.Writer
+}
+
+func NewGraphML(writer io.Writer) *GraphML {
+	return &GraphML{graph: newGraph(), writer: writer}
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLElement struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr,omitempty"`
+	Target string        `xml:"target,attr,omitempty"`
+	Data   []graphMLData `xml:"data"`
+}
+
+// graphMLElements writes the elements with the keys of the given attributes, which are prefixed
+// by whether they belong to nodes or edges, since each key is only for one of them.
+func graphMLElements(prefix string, elements []networkElement, attributes []networkAttribute) []graphMLElement {
+	res := make([]graphMLElement, 0, len(elements))
+	for _, e := range elements {
+		g := graphMLElement{ID: e.id, Source: e.source, Target: e.target}
+		if prefix == "n" {
+			g.Data = append(g.Data, graphMLData{Key: "n_label", Value: e.label})
+		} else {
+			g.Data = append(g.Data, graphMLData{Key: "e_predicate", Value: e.kind})
+		}
+		if e.start != nil {
+			g.Data = append(g.Data, graphMLData{Key: "n_start", Value: strconv.Itoa(*e.start)})
+		}
+		if e.end != nil {
+			g.Data = append(g.Data, graphMLData{Key: "n_end", Value: strconv.Itoa(*e.end)})
+		}
+		for _, a := range attributes {
+			if v, ok := e.values[a.name]; ok {
+				g.Data = append(g.Data, graphMLData{Key: prefix + "_" + a.name, Value: v})
+			}
+		}
+		res = append(res, g)
+	}
+	return res
+}
+
+func graphMLKeys(prefix, class string, attributes []networkAttribute) []graphMLKey {
+	res := []graphMLKey{}
+	for _, a := range attributes {
+		t, ok := graphMLTypes[a.t]
+		if !ok {
+			t = "string"
+		}
+		res = append(res, graphMLKey{ID: prefix + "_" + a.name, For: class, Name: a.name, Type: t})
+	}
+	return res
+}
+
+func (Ω *GraphML) Close() error {
+	n := newNetwork(Ω.graph)
+	document := struct {
+		XMLName   xml.Name     `xml:"graphml"`
+		Namespace string       `xml:"xmlns,attr"`
+		Keys      []graphMLKey `xml:"key"`
+		Graph     struct {
+			ID          string           `xml:"id,attr"`
+			EdgeDefault string           `xml:"edgedefault,attr"`
+			Nodes       []graphMLElement `xml:"node"`
+			Edges       []graphMLElement `xml:"edge"`
+		} `xml:"graph"`
+	}{Namespace: "http://graphml.graphdrawing.org/xmlns"}
+	document.Keys = append([]graphMLKey{
+		{ID: "n_label", For: "node", Name: "label", Type: "string"},
+		{ID: "n_start", For: "node", Name: "start", Type: "long"},
+		{ID: "n_end", For: "node", Name: "end", Type: "long"},
+		{ID: "e_predicate", For: "edge", Name: "predicate", Type: "string"},
+	}, append(graphMLKeys("n", "node", n.nodeAttributes), graphMLKeys("e", "edge", n.edgeAttributes)...)...)
+	document.Graph.ID = "wikivents"
+	document.Graph.EdgeDefault = "directed"
+	document.Graph.Nodes = graphMLElements("n", n.nodes, n.nodeAttributes)
+	document.Graph.Edges = graphMLElements("e", n.edges, n.edgeAttributes)
+	return writeXML(Ω.writer, document, "graphml")
+}
+
+func predicateStrings(predicates []parse.Predicate) []string {
+	res := make([]string, len(predicates))
+	for i, p := range predicates {
+		res[i] = string(p)
+	}
+	return res
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// searchMapping is the index mapping of the bulk documents, with the labels and descriptions of
+// each language as text fields.
+const searchMapping = `{
+  "mappings": {
+    "dynamic_templates": [
+      {
+        "languages": {
+          "path_match": "labels.*",
+          "mapping": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}}
+        }
+      },
+      {
+        "descriptions": {
+          "path_match": "descriptions.*",
+          "mapping": {"type": "text"}
+        }
+      }
+    ],
+    "properties": {
+      "id": {"type": "keyword"},
+      "label": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}},
+      "description": {"type": "text"},
+      "aliases": {"type": "text"},
+      "types": {"type": "keyword"},
+      "start_year": {"type": "integer"},
+      "end_year": {"type": "integer"},
+      "location": {"type": "geo_point"},
+      "participants": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}},
+      "participant_ids": {"type": "keyword"}
+    }
+  }
+}
+`
+
+// searchLoadScript creates the index with its mapping and loads each file of documents, against
+// the host and index given as its arguments.
+const searchLoadScript = `#!/bin/sh
+# Creates an Elasticsearch or OpenSearch index of the wikivents documents and loads them.
+cd "$(dirname "$0")"
+HOST="${1:-http://localhost:9200}"
+INDEX="${2:-wikivents}"
+curl -sf -XPUT "$HOST/$INDEX" -H 'Content-Type: application/json' --data-binary @wikivents.mapping.json || exit 1
+for f in wikivents.bulk.*.ndjson; do
+	curl -sf -XPOST "$HOST/$INDEX/_bulk" -H 'Content-Type: application/x-ndjson' --data-binary @"$f" > /dev/null || exit 1
+done
+`
+
+// DefaultBulkSize keeps each _bulk request well under the 100MB http.max_content_length that
+// Elasticsearch accepts by default.
+const DefaultBulkSize = 50 << 20
+
+// SearchBulk writes _bulk requests of one document for every entity, with its labels,
+// descriptions, aliases, types, years, location and the names of its participants, to numbered
+// files of at most the bulk size, such as wikivents.bulk.0001.ndjson. The index mapping and a
+// load.sh script that applies both are written alongside. Everything is held in memory until Close.
+type SearchBulk struct {
+	*graph
+	create CreateFunc
+	size   int
+}
+
+func NewSearchBulk(create CreateFunc, size int) *SearchBulk {
+	return &SearchBulk{graph: newGraph(), create: create, size: size}
+}
+
+type searchLocation struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type searchDocument struct {
+	ID             parse.EntityID    `json:"id"`
+	Label          string            `json:"label"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Description    string            `json:"description,omitempty"`
+	Descriptions   map[string]string `json:"descriptions,omitempty"`
+	Aliases        []string          `json:"aliases,omitempty"`
+	Types          []string          `json:"types,omitempty"`
+	StartYear      *int              `json:"start_year,omitempty"`
+	EndYear        *int              `json:"end_year,omitempty"`
+	Location       *searchLocation   `json:"location,omitempty"`
+	Participants   []string          `json:"participants,omitempty"`
+	ParticipantIDs []parse.EntityID  `json:"participant_ids,omitempty"`
+}
+
+// languages maps the language tagged values of the predicate by language.
+func languages(n *node, p parse.Predicate) map[string]string {
+	res := map[string]string{}
+	for _, f := range n.Features[p] {
+		if _, ok := res[f.Lang]; f.Lang != "" && !ok {
+			res[f.Lang] = f.Value
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+func (Ω *SearchBulk) document(n *node) searchDocument {
+	d := searchDocument{
+		ID:           n.ID,
+		Label:        n.Label(),
+		Labels:       languages(n, parse.PredicateLabel),
+		Description:  n.Value(parse.PredicateDescription, "en"),
+		Descriptions: languages(n, parse.PredicateDescription),
+		Aliases:      n.Values(parse.PredicateAlias),
+		StartYear:    n.StartYear(),
+		EndYear:      n.EndYear(),
+	}
+	if len(d.Aliases) == 0 {
+		d.Aliases = nil
+	}
+	for _, t := range n.Types {
+		d.Types = append(d.Types, strings.TrimPrefix(string(t), "t_"))
+	}
+	if geometry, ok := n.Geometry(); ok {
+		if lng, lat, ok := geoPoint(geometry); ok {
+			d.Location = &searchLocation{Lat: lat, Lon: lng}
+		}
+	}
+	for _, p := range Ω.Participants(n) {
+		d.Participants = append(d.Participants, p.Label())
+		d.ParticipantIDs = append(d.ParticipantIDs, p.ID)
+	}
+	return d
+}
+
+// bulkFile opens the numbered file of bulk documents.
+func (Ω *SearchBulk) bulkFile(n int) (*bufio.Writer, error) {
+	w, err := Ω.create(fmt.Sprintf("wikivents.bulk.%04d.ndjson", n))
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewWriter(w), nil
+}
+
+func (Ω *SearchBulk) Close() error {
+	files, written := 1, 0
+	w, err := Ω.bulkFile(files)
+	if err != nil {
+		return err
+	}
+	for _, n := range Ω.Nodes() {
+		if n.ID.IsReference() {
+			continue
+		}
+		// The action and its document are written together, so that no request splits them.
+		request := bytes.NewBuffer(nil)
+		encoder := json.NewEncoder(request)
+		action := map[string]map[string]parse.EntityID{"index": {"_id": n.ID}}
+		if err := encoder.Encode(action); err != nil {
+			return errors.Wrapf(err, "could not write action of %s", n.ID)
+		}
+		if err := encoder.Encode(Ω.document(n)); err != nil {
+			return errors.Wrapf(err, "could not write document of %s", n.ID)
+		}
+		if written > 0 && written+request.Len() > Ω.size {
+			if err := w.Flush(); err != nil {
+				return errors.Wrap(err, "could not write bulk documents")
+			}
+			files, written = files+1, 0
+			if w, err = Ω.bulkFile(files); err != nil {
+				return err
+			}
+		}
+		count, err := w.Write(request.Bytes())
+		if err != nil {
+			return errors.Wrap(err, "could not write bulk documents")
+		}
+		written += count
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "could not write bulk documents")
+	}
+
+	for name, content := range map[string]string{
+		"wikivents.mapping.json": searchMapping,
+		"load.sh":                searchLoadScript,
+	} {
+		w, err := Ω.create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			return errors.Wrapf(err, "could not write %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/heindl/wikivents/fetch/extsort"
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// UndatedShard holds the entities without a start year.
+const UndatedShard = "undated"
+
+// ShardFunc opens the sink of the named shard.
+type ShardFunc func(shard string) (parse.Sink, error)
+
+// YearShards writes each entity to the sink of the span of years its start year falls in, such as
+// 1000_1099 for spans of a hundred years, or to the undated shard. The start year of an entity is
+// only known once all of its features are, so events are sorted by entity on disk, and each entity
+// is assembled on its own at Close, without holding the graph in memory. The events of the
+// assembled entities are then sorted on disk again by shard, so that one shard is open at a time.
+type YearShards struct {
+	sync.Mutex
+	years    int
+	open     ShardFunc
+	entities *extsort.Sorter
+	// chunkSize is that of the sorters, which also sort the events of each shard together at Close.
+	chunkSize int
+	schemas   map[parse.Predicate]parse.PredicateSchema
+	// shared receives every event as well, such as a sink that writes one schema for all shards.
+	shared parse.Sink
+}
+
+func NewYearShards(years int, open ShardFunc, shared parse.Sink, chunkSize int) *YearShards {
+	return &YearShards{
+		years:     years,
+		open:      open,
+		entities:  extsort.New(chunkSize),
+		chunkSize: chunkSize,
+		schemas:   map[parse.Predicate]parse.PredicateSchema{},
+		shared:    shared,
+	}
+}
+
+// yearShard names the span of years the year falls in, rounding down for years before zero.
+func yearShard(year *int, years int) string {
+	if year == nil {
+		return UndatedShard
+	}
+	start := *year - *year%years
+	if *year%years < 0 {
+		start -= years
+	}
+	return fmt.Sprintf("%d_%d", start, start+years-1)
+}
+
+// shardEvent is an event of an entity, held on disk until Close.
+type shardEvent struct {
+	Node    *parse.EntityNode `json:"n,omitempty"`
+	Feature *parse.Feature    `json:"f,omitempty"`
+	Edge    *parse.Edge       `json:"e,omitempty"`
+}
+
+func (Ω *YearShards) add(id parse.EntityID, e shardEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrapf(err, "could not encode event of %s", id)
+	}
+	// A tab sorts before the characters of any ID, which keeps the events of an entity together.
+	return Ω.entities.Add(string(id) + "\t" + string(b))
+}
+
+func (Ω *YearShards) EntityNode(n parse.EntityNode) error {
+	return Ω.add(n.ID, shardEvent{Node: &n})
+}
+
+func (Ω *YearShards) Feature(f parse.Feature) error {
+	return Ω.add(f.Entity, shardEvent{Feature: &f})
+}
+
+func (Ω *YearShards) Edge(e parse.Edge) error {
+	return Ω.add(e.Object, shardEvent{Edge: &e})
+}
+
+func (Ω *YearShards) PredicateSchema(s parse.PredicateSchema) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	Ω.schemas[s.Predicate] = s
+	return nil
+}
+
+// eventReceiver is a sink or a graph, which receive the same events.
+type eventReceiver interface {
+	EntityNode(parse.EntityNode) error
+	Feature(parse.Feature) error
+	Edge(parse.Edge) error
+}
+
+// send passes the event to the receiver.
+func (Ω shardEvent) send(s eventReceiver) error {
+	switch {
+	case Ω.Node != nil:
+		return s.EntityNode(*Ω.Node)
+	case Ω.Feature != nil:
+		return s.Feature(*Ω.Feature)
+	case Ω.Edge != nil:
+		return s.Edge(*Ω.Edge)
+	}
+	return nil
+}
+
+// replay passes the events of the node to fn, in the order the graph holds them. The subject of
+// each edge is declared as well, since a shard may not hold its node, and the node is what carries
+// an xid.
+func (Ω *YearShards) replay(n *node, fn func(shardEvent) error) error {
+	if len(n.Types) == 0 {
+		if err := fn(shardEvent{Node: &parse.EntityNode{ID: n.ID}}); err != nil {
+			return err
+		}
+	}
+	for _, t := range n.Types {
+		if err := fn(shardEvent{Node: &parse.EntityNode{ID: n.ID, Type: t}}); err != nil {
+			return err
+		}
+	}
+	predicates := make([]parse.Predicate, 0, len(n.Features))
+	for p := range n.Features {
+		predicates = append(predicates, p)
+	}
+	sort.Slice(predicates, func(i, j int) bool { return predicates[i] < predicates[j] })
+	for _, p := range predicates {
+		for i := range n.Features[p] {
+			if err := fn(shardEvent{Feature: &n.Features[p][i]}); err != nil {
+				return err
+			}
+		}
+	}
+	subjects := map[parse.EntityID]bool{n.ID: true}
+	for i, e := range n.Edges {
+		if !subjects[e.Subject] {
+			subjects[e.Subject] = true
+			if err := fn(shardEvent{Node: &parse.EntityNode{ID: e.Subject}}); err != nil {
+				return err
+			}
+		}
+		if err := fn(shardEvent{Edge: &n.Edges[i]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// place assembles the entity from its events, replays it to the shared sink, and adds its events
+// to the sorter under its shard. The count keeps the events of each shard in the order they were
+// placed.
+func (Ω *YearShards) place(g *graph, placed *extsort.Sorter, count *int) error {
+	for _, n := range g.Nodes() {
+		name := yearShard(n.StartYear(), Ω.years)
+		if err := Ω.replay(n, func(e shardEvent) error {
+			b, err := json.Marshal(e)
+			if err != nil {
+				return errors.Wrapf(err, "could not encode event of %s", n.ID)
+			}
+			*count++
+			return placed.Add(fmt.Sprintf("%s\t%012d\t%s", name, *count, b))
+		}); err != nil {
+			return err
+		}
+		if Ω.shared != nil {
+			if err := Ω.replay(n, func(e shardEvent) error { return e.send(Ω.shared) }); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// closeShard sends the schemas to the sink and closes it.
+func closeShard(s parse.Sink, schemas []parse.PredicateSchema) error {
+	for _, schema := range schemas {
+		if err := s.PredicateSchema(schema); err != nil {
+			return err
+		}
+	}
+	return s.Close()
+}
+
+// Abort removes the events held for sorting, along with those of the shared sink.
+func (Ω *YearShards) Abort() {
+	Ω.entities.Abort()
+	if a, ok := Ω.shared.(parse.Aborter); ok {
+		a.Abort()
+	}
+}
+
+func (Ω *YearShards) Close() (err error) {
+	Ω.Lock()
+	schemas := make([]parse.PredicateSchema, 0, len(Ω.schemas))
+	for _, s := range Ω.schemas {
+		schemas = append(schemas, s)
+	}
+	Ω.Unlock()
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Predicate < schemas[j].Predicate })
+
+	// Entities are assembled in the order of their IDs, and their events sorted again by shard.
+	placed := extsort.New(Ω.chunkSize)
+	defer placed.Abort()
+	count := 0
+	var current *graph
+	id := ""
+	if err := Ω.entities.Sort(func(line string) error {
+		fields := strings.SplitN(line, "\t", 2)
+		if current == nil || id != fields[0] {
+			if current != nil {
+				if err := Ω.place(current, placed, &count); err != nil {
+					return err
+				}
+			}
+			current, id = newGraph(), fields[0]
+		}
+		e := shardEvent{}
+		if err := json.Unmarshal([]byte(fields[1]), &e); err != nil {
+			return errors.Wrapf(err, "could not decode event of %s", fields[0])
+		}
+		return e.send(current)
+	}); err != nil {
+		return err
+	}
+	if current != nil {
+		if err := Ω.place(current, placed, &count); err != nil {
+			return err
+		}
+	}
+
+	// Each shard is closed before the next is opened, so only one is open at a time.
+	var shard parse.Sink
+	defer func() {
+		if a, ok := shard.(parse.Aborter); ok && err != nil {
+			a.Abort()
+		}
+	}()
+	name := ""
+	if err := placed.Sort(func(line string) error {
+		fields := strings.SplitN(line, "\t", 3)
+		if shard == nil || name != fields[0] {
+			if shard != nil {
+				if err := closeShard(shard, schemas); err != nil {
+					return err
+				}
+			}
+			var err error
+			if shard, err = Ω.open(fields[0]); err != nil {
+				return err
+			}
+			name = fields[0]
+		}
+		e := shardEvent{}
+		if err := json.Unmarshal([]byte(fields[2]), &e); err != nil {
+			return errors.Wrapf(err, "could not decode event of shard %s", fields[0])
+		}
+		return e.send(shard)
+	}); err != nil {
+		return err
+	}
+	if shard != nil {
+		if err := closeShard(shard, schemas); err != nil {
+			return err
+		}
+		shard = nil
+	}
+	if Ω.shared != nil {
+		return closeShard(Ω.shared, schemas)
+	}
+	return nil
+}
@@ -0,0 +1,436 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/stretchr/testify/assert"
+)
+
+// send passes a small graph of the Battle of Hastings to the sink.
+func send(t *testing.T, s parse.Sink) {
+	assert.NoError(t, s.EntityNode(parse.EntityNode{ID: "Q48314", Type: "t_battle"}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_label", EntityType: "t_battle", Value: `Battle of "Hastings"`, Lang: "en", Type: parse.SchemaTypeString}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_point_in_time", EntityType: "t_battle", Value: "1066", Type: parse.SchemaTypeInt}))
+	assert.NoError(t, s.EntityNode(parse.EntityNode{ID: "Q102140", Type: "t_human"}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q102140", Predicate: "f_label", EntityType: "t_human", Value: "William the Conqueror", Type: parse.SchemaTypeString}))
+	assert.NoError(t, s.Edge(parse.Edge{Object: "Q48314", Predicate: "e_participant", EntityType: "t_battle", Subject: "Q102140"}))
+	assert.NoError(t, s.Edge(parse.Edge{Object: "Q48314", Predicate: "e_participant", EntityType: "t_battle", Subject: "Q102140"}))
+	assert.NoError(t, s.PredicateSchema(parse.PredicateSchema{Predicate: "f_point_in_time", Type: parse.SchemaTypeInt}))
+	assert.NoError(t, s.Close())
+}
+
+func TestTurtle(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	send(t, NewTurtle(b, "http://example.org/wv#"))
+	assert.Equal(t, `@prefix wd: <http://www.wikidata.org/entity/> .
+@prefix wdref: <http://www.wikidata.org/reference/> .
+@prefix wds: <http://www.wikidata.org/entity/statement/> .
+@prefix wv: <http://example.org/wv#> .
+
+wd:Q48314 a wv:t_battle .
+wd:Q48314 wv:f_label "Battle of \"Hastings\""@en .
+wd:Q48314 wv:f_point_in_time "1066"^^<http://www.w3.org/2001/XMLSchema#integer> .
+wd:Q102140 a wv:t_human .
+wd:Q102140 wv:f_label "William the Conqueror" .
+wd:Q48314 wv:e_participant wd:Q102140 .
+`, b.String())
+}
+
+func TestJSONLD(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	send(t, NewJSONLD(b, "http://example.org/wv#"))
+
+	var doc struct {
+		Context map[string]string        `json:"@context"`
+		Graph   []map[string]interface{} `json:"@graph"`
+	}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &doc))
+	assert.Equal(t, "http://example.org/wv#", doc.Context["@vocab"])
+	assert.Len(t, doc.Graph, 6)
+	assert.Equal(t, map[string]interface{}{
+		"@id":           "wd:Q48314",
+		"e_participant": map[string]interface{}{"@id": "wd:Q102140"},
+	}, doc.Graph[5])
+
+	b.Reset()
+	assert.NoError(t, NewJSONLD(b, DefaultNamespace).Close())
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &doc))
+}
+
+func TestDgraphJSON(t *testing.T) {
+	b, schema := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	s := NewDgraphJSON(b, parse.NewDgraphSchema(schema, nil), 1)
+	send(t, s)
+	assert.Equal(t, `f_point_in_time: int @index(int) .
+
+type t_battle {
+	e_participant
+	f_label
+	f_point_in_time
+}
+
+type t_human {
+	f_label
+}
+`, schema.String())
+
+	objects := []map[string]interface{}{}
+	decoder := json.NewDecoder(b)
+	for decoder.More() {
+		o := map[string]interface{}{}
+		assert.NoError(t, decoder.Decode(&o))
+		objects = append(objects, o)
+	}
+	// The chunk holds one entity, so the battle is written again when the participant edge arrives.
+	assert.Len(t, objects, 3)
+	assert.Equal(t, map[string]interface{}{
+		"uid":             "_:Q48314",
+		"dgraph.type":     []interface{}{"t_battle"},
+		"t_battle":        "",
+		"f_label@en":      `Battle of "Hastings"`,
+		"f_point_in_time": float64(1066),
+	}, objects[0])
+	assert.Equal(t, map[string]interface{}{
+		"uid":         "_:Q102140",
+		"dgraph.type": []interface{}{"t_human"},
+		"t_human":     "",
+		"f_label":     "William the Conqueror",
+	}, objects[1])
+	assert.Equal(t, map[string]interface{}{
+		"uid":           "_:Q48314",
+		"e_participant": []interface{}{map[string]interface{}{"uid": "_:Q102140"}},
+	}, objects[2])
+}
+
+// files collects the files written by a sink that creates several.
+type files map[string]*bytes.Buffer
+
+func (Ω files) create(name string) (io.Writer, error) {
+	Ω[name] = bytes.NewBuffer(nil)
+	return Ω[name], nil
+}
+
+func TestNeo4jCSV(t *testing.T) {
+	f := files{}
+	s := NewNeo4jCSV(f.create)
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_coordinate_location", Value: `{"type": "Point","coordinates":[0.487500,50.911944]}`, Type: parse.SchemaTypeGeo}))
+	assert.NoError(t, s.Edge(parse.Edge{Object: "Q48314", Predicate: "e_participant", Subject: "Q102140", Facets: []parse.Facet{{Key: "stated_in", Value: "Britannica", Type: parse.SchemaTypeString}}}))
+	send(t, s)
+
+	assert.Equal(t, `id:ID,:LABEL,f_coordinate_location:point,f_label:string,f_label@en:string,f_point_in_time:long
+Q48314,t_battle,"{longitude:0.487500, latitude:50.911944}","Battle of ""Hastings""","Battle of ""Hastings""",1066
+`, f["nodes_t_battle.csv"].String())
+	assert.Equal(t, `id:ID,:LABEL,f_label:string
+Q102140,t_human,William the Conqueror
+`, f["nodes_t_human.csv"].String())
+	assert.Equal(t, `:START_ID,:END_ID,:TYPE,stated_in:string
+Q48314,Q102140,e_participant,Britannica
+`, f["relationships_e_participant.csv"].String())
+	assert.Contains(t, f["import.sh"].String(), `  --nodes=nodes_t_battle.csv \
+  --nodes=nodes_t_human.csv \
+  --relationships=relationships_e_participant.csv \
+`)
+}
+
+func TestNeo4jColumnConflict(t *testing.T) {
+	f := files{}
+	s := NewNeo4jCSV(f.create)
+	assert.NoError(t, s.EntityNode(parse.EntityNode{ID: "Q48314", Type: "t_battle"}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_point_in_time", EntityType: "t_battle", Value: "circa 1066", Type: parse.SchemaTypeString}))
+	assert.NoError(t, s.EntityNode(parse.EntityNode{ID: "Q83224", Type: "t_battle"}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q83224", Predicate: "f_point_in_time", EntityType: "t_battle", Value: "1000", Type: parse.SchemaTypeInt}))
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, `id:ID,:LABEL,f_point_in_time:string
+Q48314,t_battle,circa 1066
+Q83224,t_battle,1000
+`, f["nodes_t_battle.csv"].String())
+}
+
+func TestSQLite(t *testing.T) {
+	if !SQLiteSupported {
+		t.Skip("sqlite needs cgo")
+	}
+	dir, err := ioutil.TempDir("", "wikivents")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := NewSQLite(filepath.Join(dir, "wikivents.db"))
+	assert.NoError(t, err)
+	send(t, s)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "wikivents.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var year int
+	var label string
+	assert.NoError(t, db.QueryRow(`SELECT year, label FROM events_by_year`).Scan(&year, &label))
+	assert.Equal(t, 1066, year)
+	assert.Equal(t, `Battle of "Hastings"`, label)
+
+	var subject string
+	assert.NoError(t, db.QueryRow(`SELECT subject_label FROM relations WHERE predicate = 'e_participant'`).Scan(&subject))
+	assert.Equal(t, "William the Conqueror", subject)
+
+	var types int
+	assert.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM entity_types`).Scan(&types))
+	assert.Equal(t, 2, types)
+}
+
+// sendLocated adds coordinates and an end year to the graph of send.
+func sendLocated(t *testing.T, s parse.Sink) {
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_coordinate_location", Value: `{"type": "Point","coordinates":[0.487500,50.911944]}`, Type: parse.SchemaTypeGeo}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_end_time", Property: "P582", Value: "1066", Type: parse.SchemaTypeInt}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_start_time", Property: "P580", Value: "1065", Type: parse.SchemaTypeInt}))
+	send(t, s)
+}
+
+func TestGeoJSON(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	sendLocated(t, NewGeoJSON(b))
+	assert.JSONEq(t, `{"type": "FeatureCollection", "features": [{
+		"type": "Feature",
+		"id": "Q48314",
+		"geometry": {"type": "Point", "coordinates": [0.4875, 50.911944]},
+		"properties": {
+			"label": "Battle of \"Hastings\"",
+			"types": ["battle"],
+			"start_year": 1066,
+			"end_year": 1066,
+			"participants": ["William the Conqueror"]
+		}
+	}]}`, b.String())
+}
+
+func TestKML(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	sendLocated(t, NewKML(b))
+	assert.Contains(t, b.String(), `<Placemark id="Q48314">
+      <name>Battle of &#34;Hastings&#34;</name>
+      <TimeSpan>
+        <begin>1066</begin>
+        <end>1066</end>
+      </TimeSpan>`)
+	assert.Contains(t, b.String(), `<coordinates>0.487500,50.911944</coordinates>`)
+	assert.Equal(t, "-0044", xsdYear(-44))
+}
+
+func TestSearchBulk(t *testing.T) {
+	f := files{}
+	s := NewSearchBulk(f.create, DefaultBulkSize)
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_label", Value: "Schlacht bei Hastings", Lang: "de", Type: parse.SchemaTypeString}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_alias", Value: "Hastings", Type: parse.SchemaTypeStringList}))
+	sendLocated(t, s)
+
+	lines := strings.Split(strings.TrimSpace(f["wikivents.bulk.0001.ndjson"].String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, `{"index":{"_id":"Q102140"}}`, lines[0])
+	assert.Equal(t, `{"index":{"_id":"Q48314"}}`, lines[2])
+	assert.JSONEq(t, `{
+		"id": "Q48314",
+		"label": "Battle of \"Hastings\"",
+		"labels": {"de": "Schlacht bei Hastings", "en": "Battle of \"Hastings\""},
+		"aliases": ["Hastings"],
+		"types": ["battle"],
+		"start_year": 1066,
+		"end_year": 1066,
+		"location": {"lat": 50.911944, "lon": 0.4875},
+		"participants": ["William the Conqueror"],
+		"participant_ids": ["Q102140"]
+	}`, lines[3])
+
+	var mapping map[string]interface{}
+	assert.NoError(t, json.Unmarshal(f["wikivents.mapping.json"].Bytes(), &mapping))
+	assert.Contains(t, f["load.sh"].String(), "for f in wikivents.bulk.*.ndjson; do")
+
+	// Each file holds whole requests, and starts a new one rather than pass the size.
+	f = files{}
+	sendLocated(t, NewSearchBulk(f.create, 1))
+	for _, name := range []string{"wikivents.bulk.0001.ndjson", "wikivents.bulk.0002.ndjson"} {
+		lines := strings.Split(strings.TrimSpace(f[name].String()), "\n")
+		assert.Len(t, lines, 2, name)
+		assert.Contains(t, lines[0], `{"index":`, name)
+	}
+}
+
+func TestDocuments(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	send(t, NewDocuments(b))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.JSONEq(t, `{
+		"id": "Q48314",
+		"label": "Battle of \"Hastings\"",
+		"types": ["battle"],
+		"start_year": 1066,
+		"features": {
+			"f_label": [{"value": "Battle of \"Hastings\"", "lang": "en"}],
+			"f_point_in_time": [{"value": 1066}]
+		},
+		"edges": {
+			"e_participant": [{"id": "Q102140", "label": "William the Conqueror", "type": "human"}]
+		}
+	}`, lines[0])
+	assert.JSONEq(t, `{
+		"id": "Q102140",
+		"label": "William the Conqueror",
+		"types": ["human"],
+		"features": {"f_label": [{"value": "William the Conqueror"}]},
+		"edges": {}
+	}`, lines[1])
+}
+
+func TestChronology(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	s := NewChronology(b, 2)
+	day := []parse.Facet{{Key: "date", Value: "1066-10-14T00:00:00Z", Type: parse.SchemaTypeString}, {Key: "precision", Value: "11", Type: parse.SchemaTypeInt}}
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_point_in_time", Property: "P585", Value: "1066", Type: parse.SchemaTypeInt, Facets: day}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q48314", Predicate: "f_start_time", Property: "P580", Value: "1065", Type: parse.SchemaTypeInt}))
+	assert.NoError(t, s.EntityNode(parse.EntityNode{ID: "Q1048", Type: "t_human"}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q1048", Predicate: "f_date_of_birth", Property: "P569", Value: "-0099", Type: parse.SchemaTypeInt, Facets: []parse.Facet{{Key: "date", Value: "-0099-07-12T00:00:00Z", Type: parse.SchemaTypeString}}}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q1048", Predicate: "f_label", Value: "Gaius Iulius Caesar", Lang: "la", Type: parse.SchemaTypeString}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q1048", Predicate: "f_label", Value: "Julius Caesar", Lang: "en", Type: parse.SchemaTypeString}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q9", Predicate: "f_point_in_time", Property: "P585", Value: "1066", Type: parse.SchemaTypeInt, Facets: day}))
+	send(t, s)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.JSONEq(t, `{"id": "Q1048", "label": "Julius Caesar", "types": ["human"], "date": "-0099-07-12T00:00:00Z", "year": -99, "property": "P569"}`, lines[0])
+	assert.JSONEq(t, `{"id": "Q48314", "label": "Battle of \"Hastings\"", "types": ["battle"], "date": "1066-10-14T00:00:00Z", "year": 1066, "precision": 11, "property": "P585"}`, lines[1])
+	assert.JSONEq(t, `{"id": "Q9", "label": "Q9", "types": [], "date": "1066-10-14T00:00:00Z", "year": 1066, "precision": 11, "property": "P585"}`, lines[2])
+	assert.True(t, chronologyKey(-100, "-0100-12-31T00:00:00Z") < chronologyKey(-99, "-0099-01-01T00:00:00Z"))
+	assert.True(t, chronologyKey(-1, "-0001") < chronologyKey(0, "0000"))
+}
+
+func TestTimeline(t *testing.T) {
+	f := files{}
+	s := NewTimeline(f.create)
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q1048", Predicate: "f_date_of_death", Property: "P570", Value: "-0043", Type: parse.SchemaTypeInt}))
+	assert.NoError(t, s.Feature(parse.Feature{Entity: "Q1048", Predicate: "f_date_of_birth", Property: "P569", Value: "-0099", Type: parse.SchemaTypeInt, Facets: []parse.Facet{
+		{Key: "date", Value: "-0099-07-12T00:00:00Z", Type: parse.SchemaTypeString},
+		{Key: "precision", Value: "11", Type: parse.SchemaTypeInt},
+	}}))
+	sendLocated(t, s)
+
+	assert.JSONEq(t, `{"events": [
+		{
+			"unique_id": "Q1048",
+			"start_date": {"year": -99, "month": 7, "day": 12, "display_date": "12 July 100 BCE"},
+			"end_date": {"year": -43, "display_date": "44 BCE"},
+			"text": {"headline": "Q1048"},
+			"group": "other"
+		},
+		{
+			"unique_id": "Q48314",
+			"start_date": {"year": 1066, "display_date": "1066"},
+			"end_date": {"year": 1066, "display_date": "1066"},
+			"text": {"headline": "Battle of \"Hastings\""},
+			"group": "battle"
+		}
+	]}`, f["wikivents.timeline.json"].String())
+
+	assert.JSONEq(t, `{
+		"items": [
+			{"id": "Q1048", "content": "Q1048", "title": "12 July 100 BCE – 44 BCE", "start": "-000099-07-12T00:00:00Z", "end": "-000042-01-01T00:00:00Z", "type": "range", "group": "other"},
+			{"id": "Q48314", "content": "Battle of \"Hastings\"", "title": "1066 – 1066", "start": "1066-01-01T00:00:00Z", "end": "1067-01-01T00:00:00Z", "type": "range", "group": "battle"}
+		],
+		"groups": [{"id": "battle", "content": "battle"}, {"id": "other", "content": "other"}]
+	}`, f["wikivents.vis.json"].String())
+	assert.Equal(t, "1 BCE", (&eventDate{Year: 0}).Label())
+}
+
+func TestGEXF(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	s := NewGEXF(b)
+	assert.NoError(t, s.Edge(parse.Edge{Object: "Q48314", Predicate: "e_participant", Subject: "Q102140", Facets: []parse.Facet{{Key: "stated_in", Value: "Britannica", Type: parse.SchemaTypeString}}}))
+	sendLocated(t, s)
+
+	assert.Contains(t, b.String(), `<graph defaultedgetype="directed" mode="dynamic" timeformat="double">`)
+	assert.Contains(t, b.String(), `<attribute id="f_point_in_time" title="f_point_in_time" type="long"></attribute>`)
+	assert.Contains(t, b.String(), `<attribute id="types" title="types" type="liststring"></attribute>`)
+	assert.Contains(t, b.String(), `<node id="Q48314" label="Battle of &#34;Hastings&#34;" start="1066" end="1066">`)
+	assert.Contains(t, b.String(), `<attvalue for="types" value="t_battle"></attvalue>`)
+	assert.Contains(t, b.String(), `<edge id="0" source="Q48314" target="Q102140" kind="e_participant">
+        <attvalues>
+          <attvalue for="stated_in" value="Britannica"></attvalue>`)
+	assert.Equal(t, 1, strings.Count(b.String(), "<edge "))
+}
+
+func TestGraphML(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	sendLocated(t, NewGraphML(b))
+
+	assert.Contains(t, b.String(), `<key id="n_f_point_in_time" for="node" attr.name="f_point_in_time" attr.type="long"></key>`)
+	assert.Contains(t, b.String(), `<node id="Q48314">
+      <data key="n_label">Battle of &#34;Hastings&#34;</data>
+      <data key="n_start">1066</data>
+      <data key="n_end">1066</data>`)
+	assert.Contains(t, b.String(), `<edge id="0" source="Q48314" target="Q102140">
+      <data key="e_predicate">e_participant</data>
+    </edge>`)
+}
+
+// closedSink counts the sinks that are open.
+type closedSink struct {
+	parse.Sink
+	open *int
+}
+
+func (Ω closedSink) Close() error {
+	*Ω.open--
+	return Ω.Sink.Close()
+}
+
+func TestYearShards(t *testing.T) {
+	buffers := map[string]*bytes.Buffer{}
+	schema := bytes.NewBuffer(nil)
+	open, mostOpen := 0, 0
+	send(t, NewYearShards(100, func(shard string) (parse.Sink, error) {
+		buffers[shard] = bytes.NewBuffer(nil)
+		if open++; open > mostOpen {
+			mostOpen = open
+		}
+		return closedSink{Sink: parse.NewRDFSink(buffers[shard], parse.NewDgraphSchema(ioutil.Discard, nil), parse.NodeModeIRI), open: &open}, nil
+	}, parse.NewRDFSink(ioutil.Discard, parse.NewDgraphSchema(schema, nil), parse.NodeModeIRI), 2))
+
+	assert.Len(t, buffers, 2)
+	assert.Equal(t, 0, open)
+	assert.Equal(t, 1, mostOpen)
+	assert.Equal(t, `<http://www.wikidata.org/entity/Q48314> <dgraph.type> "t_battle" .
+<http://www.wikidata.org/entity/Q48314> <t_battle> "" .
+<http://www.wikidata.org/entity/Q48314> <f_label> "Battle of \"Hastings\""@en .
+<http://www.wikidata.org/entity/Q48314> <f_point_in_time> "1066" .
+<http://www.wikidata.org/entity/Q48314> <e_participant> <http://www.wikidata.org/entity/Q102140> .
+`, buffers["1000_1099"].String())
+	assert.Contains(t, buffers[UndatedShard].String(), `"William the Conqueror"`)
+	assert.Contains(t, schema.String(), "f_point_in_time: int")
+
+	// Each shard declares the xid of the subjects of its edges, whose nodes may be in another shard.
+	buffers = map[string]*bytes.Buffer{}
+	send(t, NewYearShards(100, func(shard string) (parse.Sink, error) {
+		buffers[shard] = bytes.NewBuffer(nil)
+		return parse.NewRDFSink(buffers[shard], parse.NewDgraphSchema(ioutil.Discard, nil), parse.NodeModeXID), nil
+	}, nil, 2))
+	assert.Contains(t, buffers["1000_1099"].String(), `_:Q102140 <xid> "http://www.wikidata.org/entity/Q102140" .`)
+	assert.Contains(t, buffers["1000_1099"].String(), `_:Q48314 <e_participant> _:Q102140 .`)
+
+	for year, shard := range map[int]string{-44: "-100_-1", -100: "-100_-1", -101: "-200_-101", 0: "0_99"} {
+		year := year
+		assert.Equal(t, shard, yearShard(&year, 100))
+	}
+}
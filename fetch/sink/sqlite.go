@@ -0,0 +1,249 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+//go:build cgo
+// +build cgo
+
+package sink
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	// Registers the sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// SQLiteSupported is whether the sqlite format was compiled in, since the driver needs cgo.
+const SQLiteSupported = true
+
+const sqliteTables = `
+CREATE TABLE entities (
+	id TEXT PRIMARY KEY,
+	label TEXT,
+	description TEXT
+);
+CREATE TABLE types (
+	entity TEXT NOT NULL,
+	type TEXT NOT NULL,
+	PRIMARY KEY (entity, type)
+);
+CREATE TABLE features (
+	entity TEXT NOT NULL,
+	predicate TEXT NOT NULL,
+	property TEXT,
+	value TEXT NOT NULL,
+	lang TEXT NOT NULL,
+	type TEXT NOT NULL,
+	int_value INTEGER,
+	float_value REAL,
+	bool_value INTEGER,
+	longitude REAL,
+	latitude REAL,
+	facets TEXT,
+	UNIQUE (entity, predicate, value, lang)
+);
+CREATE TABLE edges (
+	object TEXT NOT NULL,
+	predicate TEXT NOT NULL,
+	property TEXT,
+	subject TEXT NOT NULL,
+	facets TEXT,
+	UNIQUE (object, predicate, subject)
+);
+CREATE TABLE predicates (
+	predicate TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	lang INTEGER NOT NULL
+);
+`
+
+// sqliteIndexes are created after the inserts, which is faster than maintaining them throughout.
+var sqliteIndexes = `
+UPDATE entities SET
+	label = (SELECT value FROM features WHERE entity = entities.id AND predicate = 'f_label' ORDER BY lang != '', lang != 'en', lang LIMIT 1),
+	description = (SELECT value FROM features WHERE entity = entities.id AND predicate = 'f_description' ORDER BY lang != '', lang != 'en', lang LIMIT 1);
+CREATE INDEX types_type ON types (type);
+CREATE INDEX features_entity ON features (entity);
+CREATE INDEX features_predicate ON features (predicate, int_value);
+CREATE INDEX features_property ON features (property, int_value);
+CREATE INDEX edges_object ON edges (object);
+CREATE INDEX edges_subject ON edges (subject);
+CREATE INDEX edges_predicate ON edges (predicate);
+
+CREATE VIEW event_years AS
+	SELECT
+		entity,
+		MIN(CASE WHEN property IN (` + sqlList(startProperties...) + `) OR predicate IN (` + sqlList(predicateStrings(startPredicates)...) + `) THEN int_value END) AS start_year,
+		MAX(CASE WHEN property IN (` + sqlList(endProperties...) + `) OR predicate IN (` + sqlList(predicateStrings(endPredicates)...) + `) THEN int_value END) AS end_year
+	FROM features
+	WHERE type = 'int'
+	GROUP BY entity
+	HAVING start_year IS NOT NULL;
+CREATE VIEW events_by_year AS
+	SELECT y.start_year AS year, y.end_year, e.id, e.label, e.description
+	FROM event_years y JOIN entities e ON e.id = y.entity
+	ORDER BY year, e.id;
+CREATE VIEW entity_types AS
+	SELECT e.id, e.label, t.type
+	FROM types t JOIN entities e ON e.id = t.entity;
+CREATE VIEW relations AS
+	SELECT o.id AS object, o.label AS object_label, r.predicate, s.id AS subject, s.label AS subject_label
+	FROM edges r JOIN entities o ON o.id = r.object JOIN entities s ON s.id = r.subject;
+`
+
+// sqlList quotes the strings as a list of SQL literals.
+func sqlList(values ...string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + strings.Replace(v, "'", "''", -1) + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// SQLite writes events into a SQLite database of entities, their types, features and edges, with
+// typed columns for numeric, boolean and coordinate values. Indexes and views, such as
+// events_by_year, are created on Close.
+type SQLite struct {
+	sync.Mutex
+	db         *sql.DB
+	tx         *sql.Tx
+	statements map[string]*sql.Stmt
+}
+
+var sqliteStatements = map[string]string{
+	"entity":    `INSERT OR IGNORE INTO entities (id) VALUES (?)`,
+	"type":      `INSERT OR IGNORE INTO types (entity, type) VALUES (?, ?)`,
+	"feature":   `INSERT OR IGNORE INTO features (entity, predicate, property, value, lang, type, int_value, float_value, bool_value, longitude, latitude, facets) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	"edge":      `INSERT OR IGNORE INTO edges (object, predicate, property, subject, facets) VALUES (?, ?, ?, ?, ?)`,
+	"predicate": `INSERT OR REPLACE INTO predicates (predicate, type, lang) VALUES (?, ?, ?)`,
+}
+
+// NewSQLite creates the database file, which must not exist yet, and its tables.
+func NewSQLite(filePath string) (*SQLite, error) {
+	db, err := sql.Open("sqlite3", filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open database %s", filePath)
+	}
+	if _, err := db.Exec(sqliteTables); err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "could not create tables in %s", filePath)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "could not begin transaction")
+	}
+	s := &SQLite{db: db, tx: tx, statements: map[string]*sql.Stmt{}}
+	for name, query := range sqliteStatements {
+		if s.statements[name], err = tx.Prepare(query); err != nil {
+			db.Close()
+			return nil, errors.Wrapf(err, "could not prepare %s statement", name)
+		}
+	}
+	return s, nil
+}
+
+func (Ω *SQLite) exec(statement string, args ...interface{}) error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	if _, err := Ω.statements[statement].Exec(args...); err != nil {
+		return errors.Wrapf(err, "could not insert %s %v", statement, args)
+	}
+	return nil
+}
+
+// sqliteFacets encodes facets as a JSON object, or NULL if there are none.
+func sqliteFacets(facets []parse.Facet) interface{} {
+	if len(facets) == 0 {
+		return nil
+	}
+	m := map[string]interface{}{}
+	for _, f := range facets {
+		m[f.Key] = jsonValue(f.Value, f.Type)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+// nullString is NULL for an empty string.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func (Ω *SQLite) EntityNode(n parse.EntityNode) error {
+	if err := Ω.exec("entity", string(n.ID)); err != nil {
+		return err
+	}
+	if n.Type == "" {
+		return nil
+	}
+	return Ω.exec("type", string(n.ID), string(n.Type))
+}
+
+func (Ω *SQLite) Feature(f parse.Feature) error {
+	var intValue, floatValue, boolValue, longitude, latitude interface{}
+	switch f.Type {
+	case parse.SchemaTypeInt:
+		if i, err := strconv.ParseInt(f.Value, 10, 64); err == nil {
+			intValue, floatValue = i, float64(i)
+		}
+	case parse.SchemaTypeFloat:
+		if v, err := strconv.ParseFloat(f.Value, 64); err == nil {
+			floatValue = v
+		}
+	case parse.SchemaTypeBool:
+		if b, err := strconv.ParseBool(f.Value); err == nil {
+			boolValue = b
+		}
+	case parse.SchemaTypeGeo:
+		if lng, lat, ok := geoPoint(f.Value); ok {
+			longitude, latitude = lng, lat
+		}
+	}
+	if err := Ω.exec("entity", string(f.Entity)); err != nil {
+		return err
+	}
+	return Ω.exec("feature",
+		string(f.Entity), string(f.Predicate), nullString(f.Property), f.Value, f.Lang, string(f.Type),
+		intValue, floatValue, boolValue, longitude, latitude, sqliteFacets(f.Facets),
+	)
+}
+
+func (Ω *SQLite) Edge(e parse.Edge) error {
+	if err := Ω.exec("entity", string(e.Object)); err != nil {
+		return err
+	}
+	return Ω.exec("edge", string(e.Object), string(e.Predicate), nullString(e.Property), string(e.Subject), sqliteFacets(e.Facets))
+}
+
+func (Ω *SQLite) PredicateSchema(s parse.PredicateSchema) error {
+	return Ω.exec("predicate", string(s.Predicate), string(s.Type), s.Lang)
+}
+
+// Close commits the inserts, creates the indexes and views, and closes the database.
+func (Ω *SQLite) Close() error {
+	Ω.Lock()
+	defer Ω.Unlock()
+	defer Ω.db.Close()
+	if err := Ω.tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit inserts")
+	}
+	if _, err := Ω.db.Exec(sqliteIndexes); err != nil {
+		return errors.Wrap(err, "could not create indexes and views")
+	}
+	return errors.Wrap(Ω.db.Close(), "could not close database")
+}
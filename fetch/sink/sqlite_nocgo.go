@@ -0,0 +1,26 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+//go:build !cgo
+// +build !cgo
+
+package sink
+
+import (
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// SQLiteSupported is whether the sqlite format was compiled in, since the driver needs cgo.
+const SQLiteSupported = false
+
+// SQLite is a stand in for the sink that is only compiled with cgo.
+type SQLite struct {
+	parse.Sink
+}
+
+func NewSQLite(filePath string) (*SQLite, error) {
+	return nil, errors.New("the sqlite format needs cgo, rebuild with CGO_ENABLED=1 and a C compiler")
+}
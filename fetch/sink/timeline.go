@@ -0,0 +1,218 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// Label formats the date for display, such as 44 BCE or 14 October 1066.
+func (Ω *eventDate) Label() string {
+	year := fmt.Sprint(Ω.Year)
+	if Ω.Year <= 0 {
+		year = fmt.Sprintf("%d BCE", 1-Ω.Year)
+	}
+	switch {
+	case Ω.Day > 0:
+		return fmt.Sprintf("%d %s %s", Ω.Day, time.Month(Ω.Month), year)
+	case Ω.Month > 0:
+		return fmt.Sprintf("%s %s", time.Month(Ω.Month), year)
+	}
+	return year
+}
+
+// time is the first moment of the date. Go, like JavaScript, numbers years astronomically, which
+// matches XSD 1.1.
+func (Ω *eventDate) time() time.Time {
+	month, day := time.Month(Ω.Month), Ω.Day
+	if month == 0 {
+		month = time.January
+	}
+	if day == 0 {
+		day = 1
+	}
+	return time.Date(Ω.Year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// after is the first moment after the date, at its precision.
+func (Ω *eventDate) after() time.Time {
+	t := Ω.time()
+	switch {
+	case Ω.Day > 0:
+		return t.AddDate(0, 0, 1)
+	case Ω.Month > 0:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(1, 0, 0)
+}
+
+// isoTime formats the time as ISO 8601, using the expanded six digit year that JavaScript parses
+// for years outside of 0 to 9999.
+func isoTime(t time.Time) string {
+	rest := t.Format("-01-02T15:04:05Z")
+	switch y := t.Year(); {
+	case y < 0:
+		return fmt.Sprintf("-%06d%s", -y, rest)
+	case y > 9999:
+		return fmt.Sprintf("+%06d%s", y, rest)
+	default:
+		return fmt.Sprintf("%04d%s", y, rest)
+	}
+}
+
+// timelineEvent is an entity with a start date, along with what both timeline formats show of it.
+type timelineEvent struct {
+	node       *node
+	start, end *eventDate
+	group      string
+}
+
+// timelineEvents returns every entity with a start date, in order of it, ignoring end dates that
+// come before it.
+func timelineEvents(g *graph) []timelineEvent {
+	res := []timelineEvent{}
+	for _, n := range g.Nodes() {
+		start := n.StartDate()
+		if start == nil || n.ID.IsReference() {
+			continue
+		}
+		e := timelineEvent{node: n, start: start, end: n.EndDate(), group: "other"}
+		if e.end != nil && e.end.after().Before(start.after()) {
+			e.end = nil
+		}
+		if len(n.Types) > 0 {
+			e.group = strings.TrimPrefix(string(n.Types[0]), "t_")
+		}
+		res = append(res, e)
+	}
+	// Nodes are sorted by ID, which the stable sort keeps between events of the same date.
+	sort.SliceStable(res, func(i, j int) bool { return res[i].start.time().Before(res[j].start.time()) })
+	return res
+}
+
+// Timeline writes the events of every entity with a start date as a TimelineJS3 timeline, to
+// wikivents.timeline.json, and as vis-timeline items and groups, to wikivents.vis.json. Entities
+// are grouped by their first type, or as other if they have none. Everything is held in memory
+// until Close.
+type Timeline struct {
+	*graph
+	create CreateFunc
+}
+
+func NewTimeline(create CreateFunc) *Timeline {
+	return &Timeline{graph: newGraph(), create: create}
+}
+
+// timelineJSDate is a TimelineJS3 date, which takes astronomical years. The display date keeps
+// negative years from being shown off by one.
+type timelineJSDate struct {
+	Year        int    `json:"year"`
+	Month       int    `json:"month,omitempty"`
+	Day         int    `json:"day,omitempty"`
+	DisplayDate string `json:"display_date"`
+}
+
+func newTimelineJSDate(d *eventDate) *timelineJSDate {
+	return &timelineJSDate{Year: d.Year, Month: d.Month, Day: d.Day, DisplayDate: d.Label()}
+}
+
+type timelineJSText struct {
+	Headline string `json:"headline"`
+	Text     string `json:"text,omitempty"`
+}
+
+type timelineJSEvent struct {
+	UniqueID  parse.EntityID  `json:"unique_id"`
+	StartDate *timelineJSDate `json:"start_date"`
+	EndDate   *timelineJSDate `json:"end_date,omitempty"`
+	Text      timelineJSText  `json:"text"`
+	Group     string          `json:"group"`
+}
+
+type visItem struct {
+	ID      parse.EntityID `json:"id"`
+	Content string         `json:"content"`
+	Title   string         `json:"title"`
+	Start   string         `json:"start"`
+	End     string         `json:"end,omitempty"`
+	Type    string         `json:"type"`
+	Group   string         `json:"group"`
+}
+
+type visGroup struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
+func (Ω *Timeline) write(name string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "could not marshal %s", name)
+	}
+	w, err := Ω.create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return errors.Wrapf(err, "could not write %s", name)
+}
+
+func (Ω *Timeline) Close() error {
+	timelineJS := struct {
+		Events []timelineJSEvent `json:"events"`
+	}{Events: []timelineJSEvent{}}
+	vis := struct {
+		Items  []visItem  `json:"items"`
+		Groups []visGroup `json:"groups"`
+	}{Items: []visItem{}, Groups: []visGroup{}}
+	groups := map[string]bool{}
+
+	for _, e := range timelineEvents(Ω.graph) {
+		label, description := e.node.Label(), e.node.Value(parse.PredicateDescription, "en")
+		event := timelineJSEvent{
+			UniqueID:  e.node.ID,
+			StartDate: newTimelineJSDate(e.start),
+			Text:      timelineJSText{Headline: label, Text: description},
+			Group:     e.group,
+		}
+		// vis-timeline ends are exclusive, so a range ends after the last day, month or year.
+		item := visItem{
+			ID:      e.node.ID,
+			Content: label,
+			Title:   e.start.Label(),
+			Start:   isoTime(e.start.time()),
+			Type:    "point",
+			Group:   e.group,
+		}
+		if e.end != nil {
+			event.EndDate = newTimelineJSDate(e.end)
+			item.Title = e.start.Label() + " – " + e.end.Label()
+			item.End, item.Type = isoTime(e.end.after()), "range"
+		}
+		if description != "" {
+			item.Title += ": " + description
+		}
+		timelineJS.Events = append(timelineJS.Events, event)
+		vis.Items = append(vis.Items, item)
+		if !groups[e.group] {
+			groups[e.group] = true
+			vis.Groups = append(vis.Groups, visGroup{ID: e.group, Content: e.group})
+		}
+	}
+	sort.Slice(vis.Groups, func(i, j int) bool { return vis.Groups[i].ID < vis.Groups[j].ID })
+
+	if err := Ω.write("wikivents.timeline.json", timelineJS); err != nil {
+		return err
+	}
+	return Ω.write("wikivents.vis.json", vis)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2018 Parker Heindl. All rights reserved.
+//
+// Use of this source code is governed by the MIT License.
+// Read LICENSE.md in the project root for information.
+
+package sink
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/heindl/wikivents/fetch/parse"
+	"github.com/pkg/errors"
+)
+
+// DefaultNamespace is the IRI prefix of the f_, e_ and t_ predicates when no other is given.
+const DefaultNamespace = "https://github.com/heindl/wikivents#"
+
+const (
+	entityNamespace    = "http://www.wikidata.org/entity/"
+	referenceNamespace = "http://www.wikidata.org/reference/"
+	statementNamespace = "http://www.wikidata.org/entity/statement/"
+	xsdNamespace       = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// datatypes maps the schema types with a typed literal form to their datatype IRI.
+var datatypes = map[parse.SchemaType]string{
+	parse.SchemaTypeInt:      xsdNamespace + "integer",
+	parse.SchemaTypeFloat:    xsdNamespace + "double",
+	parse.SchemaTypeBool:     xsdNamespace + "boolean",
+	parse.SchemaTypeDateTime: xsdNamespace + "dateTime",
+	parse.SchemaTypeGeo:      "http://www.opengis.net/ont/geosparql#geoJSONLiteral",
+}
+
+// curie is the prefixed name of an entity, statement or reference node.
+func curie(id parse.EntityID) string {
+	if id.IsStatement() {
+		return "wds:" + string(id)
+	}
+	if id.IsReference() {
+		return "wdref:" + string(id)
+	}
+	return "wd:" + string(id)
+}
+
+// Turtle writes events as Turtle statements, with entities identified by their Wikidata IRI
+// and predicates within the given namespace. Facets have no equivalent and are dropped, so
+// references should be written as nodes.
+type Turtle struct {
+	sync.Mutex
+	m         *sync.Map
+	writer    io.Writer
+	namespace string
+	started   bool
+}
+
+func NewTurtle(writer io.Writer, namespace string) *Turtle {
+	return &Turtle{
+		m:         new(sync.Map),
+		writer:    writer,
+		namespace: namespace,
+	}
+}
+
+func (Ω *Turtle) write(line string) error {
+	if _, ok := Ω.m.LoadOrStore(line, struct{}{}); ok {
+		return nil
+	}
+	Ω.Lock()
+	defer Ω.Unlock()
+	if !Ω.started {
+		Ω.started = true
+		prefixes := fmt.Sprintf(
+			"@prefix wd: <%s> .\n@prefix wdref: <%s> .\n@prefix wds: <%s> .\n@prefix wv: <%s> .\n\n",
+			entityNamespace,
+			referenceNamespace,
+			statementNamespace,
+			Ω.namespace,
+		)
+		if _, err := io.WriteString(Ω.writer, prefixes); err != nil {
+			return errors.Wrap(err, "could not write turtle prefixes")
+		}
+	}
+	if _, err := io.WriteString(Ω.writer, line); err != nil {
+		return errors.Wrapf(err, "could not write [%s]", line)
+	}
+	return nil
+}
+
+func (Ω *Turtle) EntityNode(n parse.EntityNode) error {
+	if n.Type == "" {
+		return nil
+	}
+	return Ω.write(fmt.Sprintf("%s a wv:%s .\n", curie(n.ID), n.Type))
+}
+
+func (Ω *Turtle) Feature(f parse.Feature) error {
+	literal := fmt.Sprintf(`"%s"`, parse.EscapeLiteral(f.Value))
+	if datatype, ok := datatypes[f.Type]; ok {
+		literal += fmt.Sprintf("^^<%s>", datatype)
+	} else if f.Lang != "" {
+		literal += "@" + f.Lang
+	}
+	return Ω.write(fmt.Sprintf("%s wv:%s %s .\n", curie(f.Entity), f.Predicate, literal))
+}
+
+func (Ω *Turtle) Edge(e parse.Edge) error {
+	return Ω.write(fmt.Sprintf("%s wv:%s %s .\n", curie(e.Object), e.Predicate, curie(e.Subject)))
+}
+
+// PredicateSchema is ignored, as literal types are written with each value.
+func (Ω *Turtle) PredicateSchema(parse.PredicateSchema) error {
+	return nil
+}
+
+func (Ω *Turtle) Close() error {
+	return nil
+}
@@ -1,39 +1,91 @@
 module github.com/heindl/wikivents
 
+go 1.27.1
+
+require (
+	github.com/klauspost/compress v1.15.9
+	github.com/mattn/go-sqlite3 v1.14.9
+	github.com/phogolabs/parcello v0.0.0-20180518134247-bae01a3ceb41
+	github.com/pkg/errors v0.8.0
+	github.com/sirupsen/logrus v1.1.1
+	github.com/spf13/cobra v0.0.3
+	github.com/stretchr/testify v1.2.2
+	golang.org/x/sync v0.0.0-20180314180146-1d60e4601c6f
+	golang.org/x/tools v0.0.0-20181016205153-5ef16f43e633
+)
+
 require (
+	github.com/BurntSushi/toml v0.3.1 // indirect
+	github.com/alcortesm/tgz v0.0.0-20161220082320-9c5fe88206d7 // indirect
+	github.com/anmitsu/go-shlex v0.0.0-20161002113705-648efa622239 // indirect
+	github.com/blang/vfs v1.0.0 // indirect
+	github.com/daaku/go.zipexe v0.0.0-20150329023125-a5fe2436ffcb // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dsnet/compress v0.0.0-20171208185109-cc9eb1d7ad76 // indirect
+	github.com/emirpasic/gods v1.12.0 // indirect
+	github.com/flynn/go-shlex v0.0.0-20150515145356-3f9db97f8568 // indirect
+	github.com/fsnotify/fsnotify v1.4.7 // indirect
+	github.com/gliderlabs/ssh v0.1.1 // indirect
+	github.com/golang/protobuf v1.2.0 // indirect
 	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db // indirect
+	github.com/google/go-cmp v0.2.0 // indirect
 	github.com/google/go-github v17.0.0+incompatible // indirect
 	github.com/google/go-querystring v1.0.0 // indirect
 	github.com/google/pprof v0.0.0-20181012044636-5d8e3eb86081 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/heindl/goethe v0.0.4 // indirect
+	github.com/hpcloud/tail v1.0.0 // indirect
 	github.com/ianlancetaylor/demangle v0.0.0-20180714043527-fcd258a6f0b4 // indirect
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/jbenet/go-context v0.0.0-20150711004518-d14ea06fba99 // indirect
+	github.com/jessevdk/go-flags v1.4.0 // indirect
+	github.com/kardianos/osext v0.0.0-20170510131534-ae77be60afb1 // indirect
+	github.com/kevinburke/ssh_config v0.0.0-20180830205328-81db2a75821e // indirect
 	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	github.com/kr/pretty v0.1.0 // indirect
 	github.com/kr/pty v1.1.3 // indirect
+	github.com/kr/text v0.1.0 // indirect
+	github.com/magiconair/properties v1.8.0 // indirect
 	github.com/mattn/go-runewidth v0.0.3 // indirect
 	github.com/mholt/archiver v2.1.0+incompatible // indirect
+	github.com/mitchellh/go-homedir v1.0.0 // indirect
 	github.com/mitchellh/mapstructure v1.1.2 // indirect
 	github.com/nmiyake/pkg v0.0.0-20170627000939-b64318170fde // indirect
 	github.com/nwaples/rardecode v0.0.0-20171029023500-e06696f847ae // indirect
+	github.com/onsi/ginkgo v1.6.0 // indirect
+	github.com/onsi/gomega v1.4.2 // indirect
 	github.com/palantir/amalgomate v0.0.0-20180712214019-5ecd9b1733f4 // indirect
 	github.com/palantir/godel v2.10.0+incompatible // indirect
 	github.com/palantir/okgo v1.3.0 // indirect
 	github.com/palantir/pkg v0.0.0-20181003150427-05f37418e235 // indirect
-	github.com/phogolabs/parcello v0.0.0-20180518134247-bae01a3ceb41
+	github.com/pelletier/go-buffruneio v0.2.0 // indirect
+	github.com/pelletier/go-toml v1.2.0 // indirect
 	github.com/pierrec/lz4 v2.0.5+incompatible // indirect
-	github.com/pkg/errors v0.8.0
-	github.com/sirupsen/logrus v1.1.1
-	github.com/spf13/cobra v0.0.3
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/ryanuber/go-license v0.0.0-20180405065157-c69f41c2c8d6 // indirect
+	github.com/sergi/go-diff v1.0.0 // indirect
+	github.com/spf13/afero v1.1.2 // indirect
+	github.com/spf13/cast v1.2.0 // indirect
+	github.com/spf13/jwalterweatherman v1.0.0 // indirect
 	github.com/spf13/pflag v1.0.3 // indirect
 	github.com/spf13/viper v1.2.1 // indirect
-	github.com/stretchr/testify v1.2.2
+	github.com/src-d/gcfg v1.3.0 // indirect
 	github.com/ulikunitz/xz v0.5.4 // indirect
+	github.com/xanzy/ssh-agent v0.2.0 // indirect
 	golang.org/x/arch v0.0.0-20180920145803-b19384d3c130 // indirect
 	golang.org/x/crypto v0.0.0-20181015023909-0c41d7ab0a0e // indirect
 	golang.org/x/net v0.0.0-20181011144130-49bb7cea24b1 // indirect
-	golang.org/x/sync v0.0.0-20180314180146-1d60e4601c6f
 	golang.org/x/sys v0.0.0-20181011152604-fa43e7bc11ba // indirect
-	golang.org/x/tools v0.0.0-20181016205153-5ef16f43e633
+	golang.org/x/text v0.3.0 // indirect
+	gopkg.in/airbrake/gobrake.v2 v2.0.9 // indirect
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/cheggaaa/pb.v1 v1.0.26 // indirect
+	gopkg.in/fsnotify.v1 v1.4.7 // indirect
+	gopkg.in/gemnasium/logrus-airbrake-hook.v2 v2.1.2 // indirect
+	gopkg.in/src-d/go-billy.v4 v4.3.0 // indirect
+	gopkg.in/src-d/go-git-fixtures.v3 v3.1.1 // indirect
 	gopkg.in/src-d/go-git.v4 v4.7.1 // indirect
+	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
+	gopkg.in/warnings.v0 v0.1.2 // indirect
+	gopkg.in/yaml.v2 v2.2.1 // indirect
 )